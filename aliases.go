@@ -0,0 +1,126 @@
+package contour
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// AliasExistsError occurs when an alias is already in use as a setting's
+// name, a short flag, or another setting's alias.
+type AliasExistsError struct {
+	k         string
+	alias     string
+	aliasName string // the setting that alias already belongs to
+}
+
+func (e AliasExistsError) Error() string {
+	return fmt.Sprintf("%s: alias %q already exists for %q", e.k, e.alias, e.aliasName)
+}
+
+// AliasConflictError occurs when a source has values for more than one of a
+// setting's aliases, but not for the setting itself, e.g. a configuration
+// file with both of the keys of a setting's aliases.
+type AliasConflictError struct {
+	k       string
+	aliases []string
+}
+
+func (e AliasConflictError) Error() string {
+	return fmt.Sprintf("%s: conflicting aliases: %s", e.k, strings.Join(e.aliases, ", "))
+}
+
+// Aliases returns setting k's aliases, in the order they were added; nil is
+// returned if k doesn't have any or if k doesn't exist in settings.
+func (s *Settings) Aliases(k string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.settings[k].Alias...)
+}
+
+// AddAlias adds aliases to setting k. An alias that is a single character is
+// an additional short flag, e.g. -V; any other alias is an additional flag
+// name, e.g. --ver. When an alias is used, the flag's setting is set; Visited
+// returns the setting's name, not the alias. A configuration file setting's
+// value can also be set using any of its aliases as the key; if both the
+// setting's name and an alias are used, the setting's name takes precedence,
+// and if more than one alias is used, but not the name, an AliasConflictError
+// is returned when settings is set.
+//
+// A SettingNotFoundError will be returned if k doesn't exist in settings. If
+// an alias is already a setting's name, short flag, or alias, an
+// AliasExistsError will be returned. If the flags have already been parsed,
+// an ErrFlagsParsed will be returned.
+func (s *Settings) AddAlias(k string, aliases ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flagsParsed {
+		return ErrFlagsParsed
+	}
+	v, ok := s.settings[k]
+	if !ok {
+		return SettingNotFoundError{k: k}
+	}
+	if !v.IsFlag && !v.IsConfFileVar {
+		return fmt.Errorf("%s: only flag and configuration file settings can have aliases", k)
+	}
+	for i, a := range aliases {
+		if a == "" {
+			return fmt.Errorf("%s: empty alias", k)
+		}
+		for _, b := range aliases[:i] {
+			if a == b {
+				return AliasExistsError{k: k, alias: a, aliasName: k}
+			}
+		}
+		if _, ok := s.settings[a]; ok {
+			return AliasExistsError{k: k, alias: a, aliasName: a}
+		}
+		if n, ok := s.aliases[a]; ok {
+			return AliasExistsError{k: k, alias: a, aliasName: n}
+		}
+		if n, ok := s.shortFlags[a]; ok {
+			return AliasExistsError{k: k, alias: a, aliasName: n}
+		}
+		if a == completionFlag && s.completionFlag {
+			return AliasExistsError{k: k, alias: a, aliasName: completionFlag}
+		}
+	}
+	for _, a := range aliases {
+		// a single character alias is also a short flag
+		if v.IsFlag && utf8.RuneCountInString(a) == 1 {
+			s.shortFlags[a] = k
+		}
+		s.aliases[a] = k
+		v.Alias = append(v.Alias, a)
+	}
+	s.settings[k] = v
+	return nil
+}
+
+// flagAliases returns the additional names of setting v's flag: its short
+// flag, if it has one, and its aliases.
+func flagAliases(v setting) []string {
+	var names []string
+	if v.Short != "" {
+		names = append(names, v.Short)
+	}
+	return append(names, v.Alias...)
+}
+
+// aliasFlags returns setting v's aliases as they are used on the command line.
+// This assumes the lock has been obtained.
+func (s *Settings) aliasFlags(v setting) []string {
+	names := make([]string, len(v.Alias))
+	for i, a := range v.Alias {
+		names[i] = s.flagPrefix(a) + a
+	}
+	return names
+}
+
+// Aliases returns the standard settings' setting k's aliases.
+func Aliases(k string) []string { return std.Aliases(k) }
+
+// AddAlias adds aliases to the standard settings' setting k. See
+// Settings.AddAlias.
+func AddAlias(k string, aliases ...string) error { return std.AddAlias(k, aliases...) }
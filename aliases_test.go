@@ -0,0 +1,181 @@
+package contour
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAddAlias(t *testing.T) {
+	tests := []struct {
+		k       string
+		aliases []string
+		err     string
+	}{
+		{"verbose", []string{"loud", "V"}, ""},
+		{"missing", []string{"m"}, "missing: setting not found"},
+		{"verbose", []string{""}, "verbose: empty alias"},
+		{"verbose", []string{"port"}, `verbose: alias "port" already exists for "port"`},
+		{"verbose", []string{"p"}, `verbose: alias "p" already exists for "port"`},
+		{"port", []string{"loud"}, `port: alias "loud" already exists for "verbose"`},
+		{"port", []string{"listen", "listen"}, `port: alias "listen" already exists for "port"`},
+		{"host", []string{"h"}, "host: only flag and configuration file settings can have aliases"},
+		{"tls", []string{"ssl"}, ""},
+	}
+	tst := New("app")
+	tst.RegisterBoolFlag("verbose", "v", false, "false", "")
+	tst.RegisterIntFlag("port", "p", 8080, "8080", "")
+	tst.RegisterBoolConfFileVar("tls", false)
+	tst.AddString("host", "localhost")
+	for _, test := range tests {
+		err := tst.AddAlias(test.k, test.aliases...)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%s %v: got %q; want %q", test.k, test.aliases, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%s %v: got no error; want %q", test.k, test.aliases, test.err)
+		}
+	}
+	if a := tst.Aliases("verbose"); !reflect.DeepEqual(a, []string{"loud", "V"}) {
+		t.Errorf("aliases: got %v; want [loud V]", a)
+	}
+	err := tst.RegisterStringFlag("loud", "", "", "", "")
+	if err == nil || err.Error() != `loud: alias "loud" already exists for "verbose"` {
+		t.Errorf("register: got %v; want alias exists error", err)
+	}
+	err = tst.RegisterStringFlag("level", "V", "", "", "")
+	if err == nil || err.Error() != `level: short flag "V" already exists for "verbose"` {
+		t.Errorf("register short: got %v; want short flag exists error", err)
+	}
+}
+
+func TestAliasFlags(t *testing.T) {
+	tests := []struct {
+		posix   bool
+		args    []string
+		verbose bool
+		port    int
+		origin  string
+		visited []string
+		err     string
+	}{
+		{false, []string{"-loud"}, true, 8080, "flag -loud", []string{"verbose"}, ""},
+		{false, []string{"-V", "-listen", "9090"}, true, 9090, "flag -V", []string{"port", "verbose"}, ""},
+		{true, []string{"--loud", "--listen=9090"}, true, 9090, "flag --loud", []string{"port", "verbose"}, ""},
+		{true, []string{"-Vv", "-P", "9090"}, true, 9090, "flag -v", []string{"port", "verbose"}, ""},
+		{true, []string{"-listen"}, false, 0, "", nil, "parse of command-line arguments failed: flag provided but not defined: -l"},
+		{true, []string{"--P"}, false, 0, "", nil, "parse of command-line arguments failed: flag provided but not defined: --P"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetErrOnMissingConfFile(false)
+		tst.SetPOSIXFlags(test.posix)
+		tst.SetUsage(func() {})
+		tst.RegisterBoolFlag("verbose", "v", false, "false", "")
+		tst.RegisterIntFlag("port", "p", 8080, "8080", "")
+		tst.AddAlias("verbose", "loud", "V")
+		tst.AddAlias("port", "listen", "P")
+		_, err := tst.ParseFlags(test.args)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%v: got %q; want %q", test.args, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%v: got no error; want %q", test.args, test.err)
+			continue
+		}
+		if v := tst.Bool("verbose"); v != test.verbose {
+			t.Errorf("%v: verbose: got %v; want %v", test.args, v, test.verbose)
+		}
+		if v := tst.Int("port"); v != test.port {
+			t.Errorf("%v: port: got %d; want %d", test.args, v, test.port)
+		}
+		if o := tst.Origin("verbose"); o != test.origin {
+			t.Errorf("%v: origin: got %q; want %q", test.args, o, test.origin)
+		}
+		if v := tst.Visited(); !reflect.DeepEqual(v, test.visited) {
+			t.Errorf("%v: visited: got %v; want %v", test.args, v, test.visited)
+		}
+	}
+}
+
+func TestAliasConfFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "app.json")
+	err = ioutil.WriteFile(fname, []byte(`{"ssl": true, "listen": "9090", "loglevel": "debug", "log-level": "info"}`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tst := New("app")
+	tst.SetConfFilename(fname)
+	tst.RegisterBoolConfFileVar("tls", false)
+	tst.RegisterStringConfFileVar("addr", "")
+	tst.RegisterStringConfFileVar("log-level", "warn")
+	tst.AddAlias("tls", "ssl")
+	tst.AddAlias("addr", "listen")
+	tst.AddAlias("log-level", "loglevel")
+	err = tst.Set()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !tst.Bool("tls") {
+		t.Error("tls: got false; want true")
+	}
+	if v := tst.String("addr"); v != "9090" {
+		t.Errorf("addr: got %q; want 9090", v)
+	}
+	// the setting's name takes precedence over its alias
+	if v := tst.String("log-level"); v != "info" {
+		t.Errorf("log-level: got %q; want info", v)
+	}
+}
+
+func TestAliasConflict(t *testing.T) {
+	tests := []struct {
+		conf string
+		src  map[string]SourceValue
+		want string
+		err  string
+	}{
+		{`{"loglevel": "debug"}`, nil, "debug", ""},
+		{`{"log-level": "info", "loglevel": "debug", "log_level": "error"}`, nil, "info", ""},
+		{`{"loglevel": "debug", "log_level": "error"}`, nil, "", "setting configuration from file failed: conf file app.json: log-level: conflicting aliases: log_level, loglevel"},
+		{`{}`, map[string]SourceValue{"loglevel": {Value: "debug"}, "log_level": {Value: "error"}}, "", "setting configuration from sources failed: source test: log-level: conflicting aliases: log_level, loglevel"},
+	}
+	for i, test := range tests {
+		tst := New("app")
+		tst.SetConfFS(fstest.MapFS{"app.json": {Data: []byte(test.conf)}})
+		tst.SetConfFilename("app.json")
+		tst.RegisterStringConfFileVar("log-level", "warn")
+		tst.AddAlias("log-level", "loglevel", "log_level")
+		if test.src != nil {
+			tst.AddSource(mapSource{name: "test", vals: test.src})
+		}
+		err := tst.Set()
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%d: got %q; want %q", i, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%d: got no error; want %q", i, test.err)
+			continue
+		}
+		if v := tst.String("log-level"); v != test.want {
+			t.Errorf("%d: log-level: got %q; want %q", i, v, test.want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package contour
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrArgsWithCommands occurs when args are registered on settings that have
+// commands, or a command is added to settings that have args. The args of a
+// command should be registered on the command.
+var ErrArgsWithCommands = errors.New("settings with commands cannot have args")
+
+// MissingArgError occurs when a required arg wasn't provided.
+type MissingArgError struct {
+	name string
+}
+
+func (e MissingArgError) Error() string {
+	return fmt.Sprintf("%s: missing required arg", e.name)
+}
+
+// UnexpectedArgError occurs when more args were provided than were
+// registered.
+type UnexpectedArgError struct {
+	arg string
+}
+
+func (e UnexpectedArgError) Error() string {
+	return fmt.Sprintf("%s: unexpected arg", e.arg)
+}
+
+// RegisterArg registers a positional arg, name, of type typ: bool, int, int64,
+// or string. Args are registered in the order that they are expected in the
+// non-flag args. A required arg must be provided; an optional arg has the
+// zero value of its type when it isn't. The last arg may be variadic, in which
+// case it gets the rest of the args and its value is a slice of its type, e.g.
+// []string, which can be gotten with Interface. Once the flags have been
+// parsed, args are gotten like any other setting, e.g. with String.
+//
+// The args are set and validated by ParseFlags: if a required arg is missing,
+// a MissingArgError is returned; if there are more args than were registered,
+// an UnexpectedArgError is returned; if an arg cannot be parsed as its type, a
+// ParseError is returned. ParseFlags still returns the non-flag args.
+//
+// A required arg cannot follow an optional arg and no arg can follow a
+// variadic arg. If a setting with the name already exists a
+// SettingExistsError will be returned. If settings has commands, an
+// ErrArgsWithCommands will be returned.
+func (s *Settings) RegisterArg(typ, name string, required, variadic bool, usage string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.commands) > 0 {
+		return ErrArgsWithCommands
+	}
+	dTyp := parseDataType(typ)
+	var v interface{}
+	switch dTyp {
+	case _bool:
+		v = false
+	case _int:
+		v = 0
+	case _int64:
+		v = int64(0)
+	case _string:
+		v = ""
+	default:
+		return fmt.Errorf("%s: unsupported arg type: %s", name, typ)
+	}
+	if len(s.args) > 0 {
+		last := s.settings[s.args[len(s.args)-1]]
+		if last.Variadic {
+			return fmt.Errorf("%s: an arg cannot follow a variadic arg", name)
+		}
+		if required && !last.Required {
+			return fmt.Errorf("%s: a required arg cannot follow an optional arg", name)
+		}
+	}
+	if variadic {
+		v = emptySlice(dTyp)
+	}
+	err := s.registerSetting(Arg, dTyp, name, "", v, "", usage, false, false, false, false)
+	if err != nil {
+		return err
+	}
+	a := s.settings[name]
+	a.IsArg = true
+	a.Required = required
+	a.Variadic = variadic
+	s.settings[name] = a
+	s.args = append(s.args, name)
+	s.useFlags = true // args are set when the flags are parsed
+	return nil
+}
+
+// emptySlice returns an empty slice of the data type.
+func emptySlice(typ dataType) interface{} {
+	switch typ {
+	case _bool:
+		return []bool{}
+	case _int:
+		return []int{}
+	case _int64:
+		return []int64{}
+	}
+	return []string{}
+}
+
+// setArgs sets the registered args from the non-flag args. If settings doesn't
+// have any args, nothing is done. This assumes the lock has been obtained.
+func (s *Settings) setArgs(args []string) error {
+	if len(s.args) == 0 {
+		return nil
+	}
+	i := 0
+	for _, name := range s.args {
+		v := s.settings[name]
+		if i >= len(args) {
+			if v.Required {
+				return MissingArgError{name: name}
+			}
+			break
+		}
+		if v.Variadic {
+			vals, err := parseArgs(v, args[i:])
+			if err != nil {
+				return err
+			}
+			v.Value = vals
+			v.Origin = fmt.Sprintf("args %d-%d", i+1, len(args))
+			if len(args)-i == 1 {
+				v.Origin = fmt.Sprintf("arg %d", i+1)
+			}
+			s.settings[name] = v
+			i = len(args)
+			break
+		}
+		val, err := parseArg(v, args[i])
+		if err != nil {
+			return err
+		}
+		v.Value = val
+		v.Origin = fmt.Sprintf("arg %d", i+1)
+		s.settings[name] = v
+		i++
+	}
+	if i < len(args) {
+		return UnexpectedArgError{arg: args[i]}
+	}
+	return nil
+}
+
+// parseArg parses the arg a as the data type of arg setting v.
+func parseArg(v setting, a string) (interface{}, error) {
+	var (
+		val interface{}
+		err error
+	)
+	switch v.Type {
+	case _bool:
+		val, err = parseBool(a)
+	case _int:
+		val, err = strconv.Atoi(a)
+	case _int64:
+		val, err = strconv.ParseInt(a, 10, 64)
+	default:
+		val = a
+	}
+	if err != nil {
+		return nil, ParseError{typ: Arg, name: v.Name, v: a, dTyp: v.Type}
+	}
+	return val, nil
+}
+
+// parseArgs parses the args as the data type of the variadic arg setting v and
+// returns them as a slice of that type.
+func parseArgs(v setting, args []string) (interface{}, error) {
+	vals := emptySlice(v.Type)
+	for _, a := range args {
+		val, err := parseArg(v, a)
+		if err != nil {
+			return nil, err
+		}
+		switch x := vals.(type) {
+		case []bool:
+			vals = append(x, val.(bool))
+		case []int:
+			vals = append(x, val.(int))
+		case []int64:
+			vals = append(x, val.(int64))
+		case []string:
+			vals = append(x, val.(string))
+		}
+	}
+	return vals, nil
+}
+
+// argForm returns how the arg setting v is shown in the usage, e.g. <src>,
+// [dst], or [files...].
+func argForm(v setting) string {
+	form := v.Name
+	if v.Variadic {
+		form += "..."
+	}
+	if v.Required {
+		return "<" + form + ">"
+	}
+	return "[" + form + "]"
+}
+
+// Args returns the names of settings' args, in the order they were
+// registered.
+func (s *Settings) Args() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.args...)
+}
+
+// RegisterArg registers a positional arg, name, of type typ for the standard
+// settings. See Settings.RegisterArg.
+func RegisterArg(typ, name string, required, variadic bool, usage string) error {
+	return std.RegisterArg(typ, name, required, variadic, usage)
+}
+
+// Args returns the names of the standard settings' args, in the order they
+// were registered.
+func Args() []string { return std.Args() }
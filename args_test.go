@@ -0,0 +1,135 @@
+package contour
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRegisterArg(t *testing.T) {
+	tests := []struct {
+		typ      string
+		name     string
+		required bool
+		variadic bool
+		err      string
+	}{
+		{"string", "src", true, false, ""},
+		{"float64", "ratio", false, false, "ratio: unsupported arg type: float64"},
+		{"string", "src", false, false, "src: arg setting exists"},
+		{"int", "count", false, false, ""},
+		{"string", "dst", true, false, "dst: a required arg cannot follow an optional arg"},
+		{"string", "files", false, true, ""},
+		{"string", "more", false, false, "more: an arg cannot follow a variadic arg"},
+	}
+	tst := New("app")
+	for _, test := range tests {
+		err := tst.RegisterArg(test.typ, test.name, test.required, test.variadic, "")
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%s: got %q; want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%s: got no error; want %q", test.name, test.err)
+		}
+	}
+	if got, want := tst.Args(), []string{"src", "count", "files"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("args: got %v; want %v", got, want)
+	}
+	_, err := tst.AddCommand("serve", "", nil)
+	if err != ErrArgsWithCommands {
+		t.Errorf("add command: got %v; want %v", err, ErrArgsWithCommands)
+	}
+
+	tst = New("app")
+	tst.AddCommand("serve", "", nil)
+	err = tst.RegisterArg("string", "src", true, false, "")
+	if err != ErrArgsWithCommands {
+		t.Errorf("register arg: got %v; want %v", err, ErrArgsWithCommands)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args   []string
+		src    string
+		count  int
+		files  []string
+		origin string
+		err    string
+	}{
+		{nil, "", 0, []string{}, "", "src: missing required arg"},
+		{[]string{"a"}, "a", 0, []string{}, "default", ""},
+		{[]string{"a", "2"}, "a", 2, []string{}, "default", ""},
+		{[]string{"a", "2", "x"}, "a", 2, []string{"x"}, "arg 3", ""},
+		{[]string{"-v", "a", "2", "x", "y"}, "a", 2, []string{"x", "y"}, "args 3-4", ""},
+		{[]string{"a", "two"}, "", 0, nil, "", `arg count: cannot parse "two" as int`},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetErrOnMissingConfFile(false)
+		tst.SetUsage(func() {})
+		tst.RegisterBoolFlag("verbose", "v", false, "false", "")
+		tst.RegisterArg("string", "src", true, false, "the source")
+		tst.RegisterArg("int", "count", false, false, "the count")
+		tst.RegisterArg("string", "files", false, true, "the files")
+		tst.Set()
+		_, err := tst.ParseFlags(test.args)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%v: got %q; want %q", test.args, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%v: got no error; want %q", test.args, test.err)
+			continue
+		}
+		if v := tst.String("src"); v != test.src {
+			t.Errorf("%v: src: got %q; want %q", test.args, v, test.src)
+		}
+		if v := tst.Int("count"); v != test.count {
+			t.Errorf("%v: count: got %d; want %d", test.args, v, test.count)
+		}
+		if v := tst.Interface("files"); !reflect.DeepEqual(v, test.files) {
+			t.Errorf("%v: files: got %v; want %v", test.args, v, test.files)
+		}
+		if v := tst.Origin("files"); v != test.origin {
+			t.Errorf("%v: files origin: got %q; want %q", test.args, v, test.origin)
+		}
+	}
+}
+
+func TestUnexpectedArg(t *testing.T) {
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.RegisterArg("string", "src", true, false, "")
+	tst.Set()
+	args, err := tst.ParseFlags([]string{"a", "b"})
+	if _, ok := err.(UnexpectedArgError); !ok || err.Error() != "b: unexpected arg" {
+		t.Errorf("got %v; want b: unexpected arg", err)
+	}
+	if !reflect.DeepEqual(args, []string{"a", "b"}) {
+		t.Errorf("got %v; want [a b]", args)
+	}
+}
+
+func TestArgsUsage(t *testing.T) {
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.RegisterArg("string", "src", true, false, "the source")
+	tst.RegisterArg("int", "files", false, true, "the files")
+	var buf bytes.Buffer
+	tst.WriteUsage(&buf)
+	expected := `Usage: app <src> [files...]
+
+Arguments:
+  <src> string    the source
+  [files...] int  the files
+`
+	if buf.String() != expected {
+		t.Errorf("got %q; want %q", buf.String(), expected)
+	}
+}
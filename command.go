@@ -0,0 +1,259 @@
+package contour
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// ErrNoCommand occurs when settings with commands is run without a command.
+var ErrNoCommand = errors.New("no command provided")
+
+// ErrNoCommandName occurs when adding a command without a name.
+var ErrNoCommandName = errors.New("no command name provided")
+
+// CommandExistsError occurs when a command being added already exists under
+// the same name.
+type CommandExistsError struct {
+	name string
+}
+
+func (e CommandExistsError) Error() string {
+	return fmt.Sprintf("%s: command exists", e.name)
+}
+
+// UnknownCommandError occurs when the command being run doesn't exist.
+type UnknownCommandError struct {
+	name string
+}
+
+func (e UnknownCommandError) Error() string {
+	return fmt.Sprintf("%s: unknown command", e.name)
+}
+
+// CommandFunc is the func that is called when a command is run. The args are
+// the non-flag args that followed the command.
+type CommandFunc func(c *Command, args []string) error
+
+// Command is a subcommand, e.g. the serve in 'app serve'. A command has its
+// own Settings, which are used to register the command's settings. The
+// command's settings inherit from the settings it was added to, its parent:
+// getting a setting that the command doesn't have returns the parent's
+// setting and the parent's flags can be used after the command's name.
+//
+// A command's environment variable names are prefixed with its parent's name
+// and the command's name, e.g. the port setting of the serve command of app
+// is APP_SERVE_PORT. A command's configuration file settings are read from the
+// parent's configuration file, using the section, or table, whose key is the
+// command's name, e.g. serve.port.
+//
+// Commands may have commands.
+type Command struct {
+	*Settings
+	name  string
+	usage string
+	run   CommandFunc
+}
+
+// Name returns the command's name.
+func (c *Command) Name() string { return c.name }
+
+// Usage returns the command's usage.
+func (c *Command) Usage() string { return c.usage }
+
+// AddCommand adds a command, name, to settings. The usage is a short
+// description of the command. When the command is run, f is called. If the
+// command has commands of its own, f may be nil. If settings already has a
+// command with that name, a CommandExistsError will be returned. If name is
+// empty, an ErrNoCommandName will be returned. If settings has args, an
+// ErrArgsWithCommands will be returned.
+func (s *Settings) AddCommand(name, usage string, f CommandFunc) (*Command, error) {
+	if name == "" {
+		return nil, ErrNoCommandName
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.commands[name]
+	if ok {
+		return nil, CommandExistsError{name: name}
+	}
+	if len(s.args) > 0 {
+		return nil, ErrArgsWithCommands
+	}
+	c := &Command{
+		Settings: New(s.name + "_" + name),
+		name:     name,
+		usage:    usage,
+		run:      f,
+	}
+	c.Settings.flagSet = flag.NewFlagSet(s.flagSet.Name()+" "+name, flag.ContinueOnError)
+	c.Settings.flagSet.Usage = c.Settings.defaultUsage
+	c.Settings.parent = s
+	c.Settings.confSection = append(append([]string{}, s.confSection...), name)
+	s.commands[name] = c
+	return c, nil
+}
+
+// Commands returns the names of settings' commands, in lexical order.
+func (s *Settings) Commands() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedCommands(s.commands)
+}
+
+// Run updates settings from its configuration file and environment
+// variables, parses args for flags, and runs the command named by the first
+// non-flag arg with the remaining args. The command's settings are updated in
+// the same way before its CommandFunc is called. The error returned by the
+// CommandFunc is returned.
+//
+// If there isn't a command arg, an ErrNoCommand is returned. If the command
+// doesn't exist, an UnknownCommandError is returned.
+func (s *Settings) Run(args []string) error {
+	err := s.Set()
+	if err != nil {
+		return err
+	}
+	args, err = s.ParseFlags(args)
+	if err != nil && err != ErrUseFlagsFalse {
+		return err
+	}
+	return s.runCommand(args)
+}
+
+// runCommand runs the command named by args[0]. The lock must not be held by
+// the caller as the command's func may use the settings.
+func (s *Settings) runCommand(args []string) error {
+	if len(args) == 0 {
+		return ErrNoCommand
+	}
+	s.mu.RLock()
+	c, ok := s.commands[args[0]]
+	s.mu.RUnlock()
+	if !ok {
+		return UnknownCommandError{name: args[0]}
+	}
+	return c.exec(args[1:])
+}
+
+// exec sets the command's settings and runs it.
+func (c *Command) exec(args []string) error {
+	c.inherit()
+	err := c.Set()
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.name, err)
+	}
+	args, err = c.ParseFlags(args)
+	if err != nil && err != ErrUseFlagsFalse {
+		return err
+	}
+	if len(args) > 0 {
+		c.mu.RLock()
+		sub, ok := c.commands[args[0]]
+		c.mu.RUnlock()
+		if ok {
+			return sub.exec(args[1:])
+		}
+	}
+	if c.run == nil {
+		if len(c.Commands()) == 0 {
+			return nil
+		}
+		return c.runCommand(args)
+	}
+	return c.run(c, args)
+}
+
+// inherit configures the command's settings to use the same configuration
+// sources as its parent and makes the parent's flags available to it.
+func (c *Command) inherit() {
+	p := c.parent
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.useConfFile = c.useConfFile && p.useConfFile
+	c.errOnMissingConfFile = p.errOnMissingConfFile
+	c.confFilename = p.confFilename
+	if c.confFilename == "" {
+		c.confFilename = p.name + "." + p.format.String()
+	}
+	c.format = p.format
+	c.confFS = p.confFS
+	c.confFilePaths = p.confFilePaths
+	c.confFilePathEnvVars = p.confFilePathEnvVars
+	c.checkWD = p.checkWD
+	c.checkExeDir = p.checkExeDir
+	c.searchPATH = p.searchPATH
+	c.useDotEnv = p.useDotEnv
+	c.dotEnvFilename = p.dotEnvFilename
+	c.dotEnvPaths = p.dotEnvPaths
+	c.dotEnvVars = p.dotEnvVars
+	c.posixFlags = p.posixFlags
+	c.overrideFlag = p.overrideFlag
+	c.profile = p.activeProfile()
+	c.profileVar = p.profileVar
+	// the parent's flags can be used after the command
+	if !p.flagsParsed {
+		return
+	}
+	c.useFlags = true
+	for k, v := range p.shortFlags {
+		if _, ok := c.shortFlags[k]; !ok {
+			c.shortFlags[k] = v
+		}
+	}
+	c.inheritFlags = true
+}
+
+// setInheritedFlags adds the parent's flags, that the command doesn't have,
+// to the command's flagSet. This assumes the lock has been obtained.
+func (s *Settings) setInheritedFlags() {
+	if s.parent == nil || !s.inheritFlags {
+		return
+	}
+	s.parent.mu.RLock()
+	defer s.parent.mu.RUnlock()
+	s.parent.flagSet.VisitAll(func(f *flag.Flag) {
+		if s.flagSet.Lookup(f.Name) == nil {
+			s.flagSet.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+}
+
+// visitedInherited records that the parent's flag, f, was visited while
+// parsing the command's args. If f isn't the parent's flag, it's one that the
+// parent inherited and is passed up. This assumes the lock has been obtained.
+func (s *Settings) visitedInherited(f *flag.Flag) {
+	p := s.parent
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	ok := p.visited(f)
+	p.mu.Unlock()
+	if !ok {
+		p.visitedInherited(f)
+	}
+}
+
+// AddCommand adds a command, name, to the standard settings. The usage is a
+// short description of the command. When the command is run, f is called. If
+// the standard settings already has a command with that name, a
+// CommandExistsError will be returned. If name is empty, an ErrNoCommandName
+// will be returned.
+func AddCommand(name, usage string, f CommandFunc) (*Command, error) {
+	return std.AddCommand(name, usage, f)
+}
+
+// Commands returns the names of the standard settings' commands, in lexical
+// order.
+func Commands() []string { return std.Commands() }
+
+// Run updates the standard settings from its configuration file and
+// environment variables, parses os.Args[1:] for flags, and runs the command
+// named by the first non-flag arg with the remaining args. If there isn't a
+// command arg, an ErrNoCommand is returned. If the command doesn't exist, an
+// UnknownCommandError is returned.
+func Run() error { return std.Run(os.Args[1:]) }
@@ -0,0 +1,233 @@
+package contour
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAddCommand(t *testing.T) {
+	tst := New("app")
+	_, err := tst.AddCommand("", "", nil)
+	if err != ErrNoCommandName {
+		t.Errorf("got %v; want %s", err, ErrNoCommandName)
+	}
+	c, err := tst.AddCommand("serve", "run the server", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c.Name() != "serve" {
+		t.Errorf("name: got %q; want \"serve\"", c.Name())
+	}
+	if c.Usage() != "run the server" {
+		t.Errorf("usage: got %q; want \"run the server\"", c.Usage())
+	}
+	if c.EnvVarName("port") != "APP_SERVE_PORT" {
+		t.Errorf("env var name: got %q; want APP_SERVE_PORT", c.EnvVarName("port"))
+	}
+	_, err = tst.AddCommand("serve", "", nil)
+	if err != (CommandExistsError{name: "serve"}) {
+		t.Errorf("got %v; want %s", err, CommandExistsError{name: "serve"})
+	}
+	tst.AddCommand("migrate", "", nil)
+	if !reflect.DeepEqual(tst.Commands(), []string{"migrate", "serve"}) {
+		t.Errorf("commands: got %v; want [migrate serve]", tst.Commands())
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "app.json")
+	err = ioutil.WriteFile(fname, []byte(`{"verbose": false, "serve": {"host": "conf.example.com", "tls": true}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("APP_SERVE_HOST", "env.example.com")
+	defer os.Unsetenv("APP_SERVE_HOST")
+
+	var (
+		ran   string
+		args  []string
+		port  int
+		host  string
+		tls   bool
+		vrbse bool
+	)
+	tst := New("app")
+	tst.SetConfFilename(fname)
+	tst.RegisterBoolFlag("verbose", "v", false, "false", "verbose output")
+	serve, _ := tst.AddCommand("serve", "run the server", func(c *Command, a []string) error {
+		ran = c.Name()
+		args = a
+		port = c.Int("port")
+		host = c.String("host")
+		tls = c.Bool("tls")
+		// inherited from the parent
+		vrbse = c.Bool("verbose")
+		return nil
+	})
+	serve.RegisterIntFlag("port", "p", 8080, "8080", "port to listen on")
+	serve.RegisterStringEnvVar("host", "localhost")
+	serve.RegisterBoolConfFileVar("tls", false)
+	tst.AddCommand("version", "print the version", func(c *Command, a []string) error {
+		ran = c.Name()
+		return nil
+	})
+
+	err = tst.Run([]string{"serve", "-p", "9090", "-v", "extra"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ran != "serve" {
+		t.Errorf("ran: got %q; want serve", ran)
+	}
+	if !reflect.DeepEqual(args, []string{"extra"}) {
+		t.Errorf("args: got %v; want [extra]", args)
+	}
+	if port != 9090 {
+		t.Errorf("port: got %d; want 9090", port)
+	}
+	if host != "env.example.com" {
+		t.Errorf("host: got %q; want env.example.com", host)
+	}
+	if !tls {
+		t.Error("tls: got false; want true")
+	}
+	if !vrbse {
+		t.Error("verbose: got false; want true")
+	}
+	if !tst.WasVisited("verbose") {
+		t.Error("verbose: expected the parent's flag to be visited")
+	}
+	if !serve.WasVisited("port") {
+		t.Error("port: expected the command's flag to be visited")
+	}
+}
+
+func TestRunCommandFlatConf(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.ini": {Data: []byte(`name = app
+
+[serve]
+port = 9090
+
+[db]
+host = db.example.com
+
+[db.migrate]
+steps = 3
+`)},
+		"app.properties": {Data: []byte("name=app\nserve.port=9090\ndb.host=db.example.com\ndb.migrate.steps=3\n")},
+	}
+	for _, fname := range []string{"app.ini", "app.properties"} {
+		for _, args := range [][]string{{"serve"}, {"db", "migrate"}} {
+			var (
+				port  int
+				host  string
+				steps int
+			)
+			tst := New("app")
+			tst.SetConfFS(fsys)
+			tst.SetConfFilename(fname)
+			tst.RegisterStringConfFileVar("name", "")
+			serve, _ := tst.AddCommand("serve", "run the server", func(c *Command, a []string) error {
+				port = c.Int("port")
+				return nil
+			})
+			serve.RegisterIntConfFileVar("port", 8080)
+			db, _ := tst.AddCommand("db", "database commands", nil)
+			db.RegisterStringConfFileVar("host", "localhost")
+			migrate, _ := db.AddCommand("migrate", "migrate the database", func(c *Command, a []string) error {
+				host = db.String("host")
+				steps = c.Int("steps")
+				return nil
+			})
+			migrate.RegisterIntConfFileVar("steps", 1)
+			err := tst.Run(args)
+			if err != nil {
+				t.Errorf("%s %v: unexpected error: %s", fname, args, err)
+				continue
+			}
+			if v := tst.String("name"); v != "app" {
+				t.Errorf("%s %v: name: got %q; want app", fname, args, v)
+			}
+			switch args[0] {
+			case "serve":
+				if port != 9090 {
+					t.Errorf("%s %v: port: got %d; want 9090", fname, args, port)
+				}
+			case "db":
+				if host != "db.example.com" {
+					t.Errorf("%s %v: host: got %q; want db.example.com", fname, args, host)
+				}
+				if steps != 3 {
+					t.Errorf("%s %v: steps: got %d; want 3", fname, args, steps)
+				}
+			}
+		}
+	}
+}
+
+func TestRunCommandErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  error
+	}{
+		{nil, ErrNoCommand},
+		{[]string{"-v"}, ErrNoCommand},
+		{[]string{"nope"}, UnknownCommandError{name: "nope"}},
+		{[]string{"db"}, ErrNoCommand},
+		{[]string{"db", "nope"}, UnknownCommandError{name: "nope"}},
+		{[]string{"db", "migrate"}, nil},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.RegisterBoolFlag("verbose", "v", false, "false", "verbose output")
+		tst.SetErrOnMissingConfFile(false)
+		db, _ := tst.AddCommand("db", "database commands", nil)
+		db.AddCommand("migrate", "migrate the database", func(c *Command, a []string) error { return nil })
+		err := tst.Run(test.args)
+		if err != test.err {
+			t.Errorf("%v: got %v; want %v", test.args, err, test.err)
+		}
+	}
+}
+
+func TestRunCommandPOSIX(t *testing.T) {
+	var (
+		port int
+		vrbs bool
+		args []string
+	)
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.SetPOSIXFlags(true)
+	tst.RegisterBoolFlag("verbose", "v", false, "false", "verbose output")
+	serve, _ := tst.AddCommand("serve", "", func(c *Command, a []string) error {
+		port = c.Int("port")
+		vrbs = c.Bool("verbose")
+		args = a
+		return nil
+	})
+	serve.RegisterIntFlag("port", "p", 8080, "8080", "port to listen on")
+	err := tst.Run([]string{"serve", "a", "-vp9090", "b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if port != 9090 {
+		t.Errorf("port: got %d; want 9090", port)
+	}
+	if !vrbs {
+		t.Error("verbose: got false; want true")
+	}
+	if !reflect.DeepEqual(args, []string{"a", "b"}) {
+		t.Errorf("args: got %v; want [a b]", args)
+	}
+}
@@ -0,0 +1,389 @@
+package contour
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrCompletion is returned by ParseFlags when the completion flag was used.
+// The completion script has been written to os.Stdout; the application should
+// exit.
+var ErrCompletion = errors.New("completion script written")
+
+// The name and usage of the built-in completion flag.
+const (
+	completionFlag  = "completion"
+	completionUsage = "write the shell completion script for bash, fish, or zsh"
+)
+
+// The shells that completion scripts can be generated for.
+var completionShells = []string{"bash", "fish", "zsh"}
+
+// UnsupportedShellError occurs when a completion script is requested for a
+// shell that isn't supported.
+type UnsupportedShellError struct {
+	shell string
+}
+
+func (e UnsupportedShellError) Error() string {
+	return fmt.Sprintf("%s: unsupported shell: must be one of %s", e.shell, strings.Join(completionShells, ", "))
+}
+
+// completionWord is a flag, or command, that can be completed along with the
+// hints for its value.
+type completionWord struct {
+	names   []string // the names as used on the command line, e.g. --port
+	usage   string
+	takesV  bool     // whether the value can be a separate arg
+	values  []string // the values that can be completed
+	isBool  bool
+	command bool
+}
+
+// WriteCompletion writes a completion script for shell, bash, zsh, or fish,
+// to w. The script completes settings' flags, both their names and short
+// names, and commands. A flag's value is completed when the flag is a bool
+// flag, bash and zsh only as the value must follow an =, or when the flag's
+// setting has choices, see SetChoices. If the shell isn't supported, an
+// UnsupportedShellError is returned.
+//
+// To use the script, it needs to be sourced by the shell, e.g. for bash:
+//    source <(app --completion bash)
+func (s *Settings) WriteCompletion(w io.Writer, shell string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.writeCompletion(w, shell)
+}
+
+// writeCompletion writes the completion script. This assumes the lock has
+// been obtained.
+func (s *Settings) writeCompletion(w io.Writer, shell string) error {
+	var buf bytes.Buffer
+	words := s.completionWords()
+	switch shell {
+	case "bash":
+		s.writeBashCompletion(&buf, words)
+	case "zsh":
+		s.writeZshCompletion(&buf, words)
+	case "fish":
+		s.writeFishCompletion(&buf, words)
+	default:
+		return UnsupportedShellError{shell: shell}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// completionWords returns settings' flags and commands, in lexical order.
+// This assumes the lock has been obtained.
+func (s *Settings) completionWords() []completionWord {
+	var flags []setting
+	for _, v := range s.settings {
+		if v.IsFlag && !v.Hidden {
+			flags = append(flags, v)
+		}
+	}
+	var words []completionWord
+	for _, v := range sortSettings(flags) {
+		word := completionWord{names: []string{s.longFlag(v.Name)}, usage: v.Usage, values: v.Choices}
+		if v.Short != "" {
+			word.names = append(word.names, "-"+v.Short)
+		}
+		word.names = append(word.names, s.aliasFlags(v)...)
+		if s.negatable(v) {
+			word.names = append(word.names, s.longFlag(negFlagPrefix+v.Name))
+		}
+		if v.Type == _bool {
+			word.isBool = true
+			word.values = []string{"true", "false"}
+		} else {
+			word.takesV = true
+		}
+		words = append(words, word)
+	}
+	if _, ok := s.settings[completionFlag]; s.completionFlag && !ok {
+		words = append(words, completionWord{
+			names:  []string{s.longFlag(completionFlag)},
+			usage:  completionUsage,
+			takesV: true,
+			values: completionShells,
+		})
+	}
+	if s.hasOverrideFlag() {
+		words = append(words, completionWord{
+			names:  []string{s.longFlag(s.overrideFlag)},
+			usage:  overrideUsage,
+			takesV: true,
+		})
+	}
+	if s.hasProfileFlag() {
+		words = append(words, completionWord{
+			names:  []string{s.longFlag(s.profileVar)},
+			usage:  profileUsage,
+			takesV: true,
+		})
+	}
+	for _, k := range sortedCommands(s.commands) {
+		words = append(words, completionWord{names: []string{k}, usage: s.commands[k].usage, command: true})
+	}
+	return words
+}
+
+// completionFuncName returns the name of the shell func for settings'
+// completion. Characters that aren't valid in a func name are replaced.
+func (s *Settings) completionFuncName() string {
+	return "_" + regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(s.name, "_") + "_completion"
+}
+
+func (s *Settings) writeBashCompletion(buf *bytes.Buffer, words []completionWord) {
+	fn := s.completionFuncName()
+	var all []string
+	fmt.Fprintf(buf, "# bash completion for %s\n\n", s.name)
+	fmt.Fprintf(buf, "%s() {\n", fn)
+	buf.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\" eq=\n")
+	buf.WriteString("    # = is a word break: --name=value is split into --name, =, and value\n")
+	buf.WriteString("    if [[ \"$cur\" == \"=\" ]]; then\n")
+	buf.WriteString("        cur= eq=1\n")
+	buf.WriteString("    elif [[ \"$prev\" == \"=\" ]]; then\n")
+	buf.WriteString("        prev=\"${COMP_WORDS[COMP_CWORD-2]}\" eq=1\n")
+	buf.WriteString("    fi\n")
+	buf.WriteString("    case \"$prev\" in\n")
+	for _, w := range words {
+		all = append(all, w.names...)
+		if len(w.values) == 0 {
+			continue
+		}
+		fmt.Fprintf(buf, "    %s)\n", strings.Join(w.names, "|"))
+		if w.isBool {
+			// a bool's value must be attached with an =
+			buf.WriteString("        if [[ -n \"$eq\" ]]; then\n")
+			fmt.Fprintf(buf, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(w.values, " "))
+			buf.WriteString("            return\n")
+			buf.WriteString("        fi\n")
+		} else {
+			fmt.Fprintf(buf, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(w.values, " "))
+			buf.WriteString("        return\n")
+		}
+		buf.WriteString("        ;;\n")
+	}
+	buf.WriteString("    esac\n")
+	fmt.Fprintf(buf, "    COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(all, " "))
+	buf.WriteString("}\n\n")
+	fmt.Fprintf(buf, "complete -o default -F %s %s\n", fn, s.name)
+}
+
+func (s *Settings) writeZshCompletion(buf *bytes.Buffer, words []completionWord) {
+	fn := s.completionFuncName()
+	var commands []string
+	fmt.Fprintf(buf, "#compdef %s\n\n", s.name)
+	fmt.Fprintf(buf, "%s() {\n", fn)
+	// short flags can only be combined, -vx, in POSIX mode
+	if s.posixFlags {
+		buf.WriteString("    _arguments -s \\\n")
+	} else {
+		buf.WriteString("    _arguments \\\n")
+	}
+	for _, w := range words {
+		if w.command {
+			// the value and its description are separated by a : and
+			// the pairs by spaces
+			commands = append(commands, zshEscape(w.names[0])+"\\:"+strings.NewReplacer(":", `\:`, " ", `\ `).Replace(zshEscape(w.usage)))
+			continue
+		}
+		excl := ""
+		if len(w.names) > 1 {
+			excl = "(" + strings.Join(w.names, " ") + ")"
+		}
+		for i, name := range w.names {
+			spec := excl + name
+			action := ""
+			switch {
+			case w.isBool && i == 0:
+				// only the flag's name can have its value attached
+				spec += "=-"
+				action = ":bool:(" + strings.Join(w.values, " ") + ")"
+			case w.takesV:
+				if i == 0 {
+					spec += "="
+				} else if s.posixFlags {
+					// a short flag's value may be attached, -p8080
+					spec += "+"
+				}
+				action = ":" + strings.TrimLeft(w.names[0], "-") + ":"
+				if len(w.values) > 0 {
+					action += "(" + strings.Join(w.values, " ") + ")"
+				}
+			}
+			fmt.Fprintf(buf, "        '%s[%s]%s' \\\n", spec, zshEscape(w.usage), action)
+		}
+	}
+	if len(commands) > 0 {
+		fmt.Fprintf(buf, "        '1:command:((%s))' \\\n", strings.Join(commands, " "))
+	}
+	buf.WriteString("        '*:file:_files'\n")
+	buf.WriteString("}\n\n")
+	fmt.Fprintf(buf, "compdef %s %s\n", fn, s.name)
+}
+
+// zshEscape escapes s for use in an _arguments spec within single quotes.
+func zshEscape(s string) string {
+	return strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
+
+func (s *Settings) writeFishCompletion(buf *bytes.Buffer, words []completionWord) {
+	fmt.Fprintf(buf, "# fish completion for %s\n\n", s.name)
+	for _, w := range words {
+		if w.command {
+			fmt.Fprintf(buf, "complete -c %s -n __fish_use_subcommand -f -a %s -d %s\n", s.name, fishQuote(w.names[0]), fishQuote(w.usage))
+			continue
+		}
+		args := []string{"complete", "-c", s.name}
+		for _, name := range w.names {
+			switch {
+			case strings.HasPrefix(name, "--"):
+				args = append(args, "-l", strings.TrimPrefix(name, "--"))
+			case len(name) == 2:
+				args = append(args, "-s", name[1:])
+			default:
+				// flag package style: -name
+				args = append(args, "-o", name[1:])
+			}
+		}
+		// fish doesn't complete optional values, so bool flags are switches
+		if w.takesV {
+			args = append(args, "-r")
+			if len(w.values) > 0 {
+				args = append(args, "-f", "-a", fishQuote(strings.Join(w.values, " ")))
+			}
+		}
+		if w.usage != "" {
+			args = append(args, "-d", fishQuote(w.usage))
+		}
+		buf.WriteString(strings.Join(args, " ") + "\n")
+	}
+}
+
+// fishQuote single quotes s for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// setCompletionFlag adds the completion flag to the flagSet, if settings is
+// set to use it and doesn't have a setting with that name. This assumes the
+// lock has been obtained.
+func (s *Settings) setCompletionFlag() {
+	if !s.completionFlag {
+		return
+	}
+	if _, ok := s.settings[completionFlag]; ok {
+		return
+	}
+	s.flagSet.String(completionFlag, "", completionUsage)
+}
+
+// completion writes the completion script for the shell that the completion
+// flag was set to, if it was used, to os.Stdout. If the script was written,
+// an ErrCompletion is returned. This assumes the lock has been obtained.
+func (s *Settings) completion() error {
+	if !s.completionFlag {
+		return nil
+	}
+	if _, ok := s.settings[completionFlag]; ok {
+		return nil
+	}
+	f := s.flagSet.Lookup(completionFlag)
+	if f == nil || f.Value.String() == "" {
+		return nil
+	}
+	err := s.writeCompletion(s.stdout(), f.Value.String())
+	if err != nil {
+		return err
+	}
+	return ErrCompletion
+}
+
+// stdout returns where settings writes output that is meant for stdout.
+func (s *Settings) stdout() io.Writer {
+	if s.out != nil {
+		return s.out
+	}
+	return os.Stdout
+}
+
+// CompletionFlag returns if settings has the built-in completion flag.
+func (s *Settings) CompletionFlag() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.completionFlag
+}
+
+// SetCompletionFlag sets if settings has the built-in completion flag,
+// --completion, or -completion if settings isn't set to use POSIX flags. When
+// the flag is used, ParseFlags writes the completion script for the shell
+// that is the flag's value to os.Stdout and returns an ErrCompletion, e.g.:
+//    app --completion bash
+// If settings has a setting named completion, the flag isn't added.
+//
+// If the flags have already been parsed, an ErrFlagsParsed is returned.
+func (s *Settings) SetCompletionFlag(b bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flagsParsed {
+		return ErrFlagsParsed
+	}
+	s.completionFlag = b
+	if b {
+		s.useFlags = true
+	}
+	return nil
+}
+
+// Choices returns the choices for setting k; nil is returned if it doesn't
+// have any or if k doesn't exist in settings.
+func (s *Settings) Choices(k string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings[k].Choices
+}
+
+// SetChoices sets the values that setting k can be set to, for settings whose
+// values are one of a set of values, e.g. a log level. The choices are used
+// as hints for completing the setting's flag value; the setting's value isn't
+// restricted to them. A SettingNotFoundError will be returned if k doesn't
+// exist in settings.
+func (s *Settings) SetChoices(k string, choices ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.settings[k]
+	if !ok {
+		return SettingNotFoundError{k: k}
+	}
+	v.Choices = append([]string(nil), choices...)
+	s.settings[k] = v
+	return nil
+}
+
+// WriteCompletion writes a completion script for shell, bash, zsh, or fish,
+// for the standard settings to w. If the shell isn't supported, an
+// UnsupportedShellError is returned.
+func WriteCompletion(w io.Writer, shell string) error { return std.WriteCompletion(w, shell) }
+
+// CompletionFlag returns if the standard settings has the built-in completion
+// flag.
+func CompletionFlag() bool { return std.CompletionFlag() }
+
+// SetCompletionFlag sets if the standard settings has the built-in completion
+// flag. If the flags have already been parsed, an ErrFlagsParsed is returned.
+func SetCompletionFlag(b bool) error { return std.SetCompletionFlag(b) }
+
+// Choices returns the choices for setting k in the standard settings.
+func Choices(k string) []string { return std.Choices(k) }
+
+// SetChoices sets the values that the standard settings' setting k can be set
+// to. A SettingNotFoundError will be returned if k doesn't exist.
+func SetChoices(k string, choices ...string) error { return std.SetChoices(k, choices...) }
@@ -0,0 +1,110 @@
+package contour
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newCompletionSettings() *Settings {
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.SetPOSIXFlags(true)
+	tst.RegisterBoolFlag("verbose", "v", false, "false", "verbose output")
+	tst.RegisterIntFlag("port", "p", 8080, "8080", "port to listen on")
+	tst.RegisterStringFlag("log-level", "", "info", "info", "the log's level")
+	tst.SetChoices("log-level", "debug", "info", "warn")
+	tst.AddCommand("serve", "run the server", nil)
+	return tst
+}
+
+func TestWriteCompletion(t *testing.T) {
+	tests := []struct {
+		shell    string
+		expected []string
+		err      string
+	}{
+		{"bash", []string{
+			"_app_completion() {",
+			"    --log-level)\n        COMPREPLY=($(compgen -W \"debug info warn\" -- \"$cur\"))",
+			"    --verbose|-v|--no-verbose)\n        if [[ -n \"$eq\" ]]; then\n            COMPREPLY=($(compgen -W \"true false\" -- \"$cur\"))",
+			"COMPREPLY=($(compgen -W \"--log-level --port -p --verbose -v --no-verbose serve\" -- \"$cur\"))",
+			"complete -o default -F _app_completion app\n",
+		}, ""},
+		{"zsh", []string{
+			"#compdef app\n",
+			"    _arguments -s \\\n",
+			`'--log-level=[the log'\''s level]:log-level:(debug info warn)' \`,
+			`'(--port -p)--port=[port to listen on]:port:' \`,
+			`'(--port -p)-p+[port to listen on]:port:' \`,
+			`'(--verbose -v --no-verbose)--verbose=-[verbose output]:bool:(true false)' \`,
+			`'(--verbose -v --no-verbose)-v[verbose output]' \`,
+			`'(--verbose -v --no-verbose)--no-verbose[verbose output]' \`,
+			`'1:command:((serve\:run\ the\ server))' \`,
+			"compdef _app_completion app\n",
+		}, ""},
+		{"fish", []string{
+			`complete -c app -l log-level -r -f -a 'debug info warn' -d 'the log\'s level'`,
+			`complete -c app -l port -s p -r -d 'port to listen on'`,
+			`complete -c app -l verbose -s v -l no-verbose -d 'verbose output'`,
+			`complete -c app -n __fish_use_subcommand -f -a 'serve' -d 'run the server'`,
+		}, ""},
+		{"tcsh", nil, "tcsh: unsupported shell: must be one of bash, fish, zsh"},
+	}
+	tst := newCompletionSettings()
+	for _, test := range tests {
+		var buf bytes.Buffer
+		err := tst.WriteCompletion(&buf, test.shell)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%s: got %q; want %q", test.shell, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%s: got no error; want %q", test.shell, test.err)
+			continue
+		}
+		for _, v := range test.expected {
+			if !strings.Contains(buf.String(), v) {
+				t.Errorf("%s: expected the script to contain %q; got\n%s", test.shell, v, buf.String())
+			}
+		}
+	}
+}
+
+func TestCompletionFlag(t *testing.T) {
+	tst := newCompletionSettings()
+	err := tst.SetCompletionFlag(true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var buf bytes.Buffer
+	tst.out = &buf
+	_, err = tst.ParseFlags([]string{"--completion", "fish"})
+	if err != ErrCompletion {
+		t.Fatalf("got %v; want %s", err, ErrCompletion)
+	}
+	if !strings.Contains(buf.String(), "complete -c app -l completion -r -f -a 'bash fish zsh'") {
+		t.Errorf("expected the fish completion script; got\n%s", buf.String())
+	}
+	err = tst.SetCompletionFlag(false)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	// without the flag
+	tst = newCompletionSettings()
+	tst.SetCompletionFlag(true)
+	args, err := tst.ParseFlags([]string{"-v", "serve"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(args) != 1 || args[0] != "serve" {
+		t.Errorf("got %v; want [serve]", args)
+	}
+	err = tst.SetCompletionFlag(true)
+	if err != ErrFlagsParsed {
+		t.Errorf("got %v; want %s", err, ErrFlagsParsed)
+	}
+}
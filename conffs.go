@@ -0,0 +1,109 @@
+package contour
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// ConfFS returns the file system that settings reads the configuration file
+// from; nil is returned if it's read from the operating system's.
+func (s *Settings) ConfFS() fs.FS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.confFS
+}
+
+// SetConfFS sets the file system that settings reads the configuration file
+// from, e.g. an embed.FS, a zip.Reader, or a fstest.MapFS; if fsys is nil, it
+// is read from the operating system's, which is the default.
+//
+// When settings has a file system, the configuration filename and the
+// configuration file paths, see SetConfFilePaths, are paths within it: they
+// are slash separated and unrooted, e.g. conf/app.yaml, see fs.ValidPath.
+// The file is looked for using its filename and then its base name within
+// each of the paths. The working directory, the executable's directory, and
+// the paths in environment variables, including $PATH, aren't searched.
+func (s *Settings) SetConfFS(fsys fs.FS) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.confFS = fsys
+}
+
+// readConfFS reads the configuration file n from settings' file system. If it
+// cannot be found, an os.PathError with an os.ErrNotExist and a list of the
+// paths checked is returned. This assumes the lock has been obtained.
+func (s *Settings) readConfFS(n string) ([]byte, error) {
+	b, err := fs.ReadFile(s.confFS, n)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return b, err
+	}
+	fname := path.Base(n)
+	for _, p := range s.confFilePaths {
+		b, err = fs.ReadFile(s.confFS, path.Join(p, fname))
+		if err == nil {
+			return b, nil
+		}
+	}
+	errS := n
+	if len(s.confFilePaths) > 0 {
+		errS += ": " + strings.Join(s.confFilePaths, "; ")
+	}
+	return nil, &os.PathError{Op: "open file", Path: errS, Err: os.ErrNotExist}
+}
+
+// SetFromReader updates the settings' configuration from the configuration
+// read from r, in the format f, instead of from the configuration file, e.g.
+// os.Stdin. The sources that have been added to settings aren't loaded; Set
+// loads them, but not the configuration file. The origin of the values is
+// "conf file " and r's name, if it has a Name method like an *os.File does,
+// otherwise, "conf reader". The active profile's values in r are set, as
+// they are from the configuration file, but the profile's own file isn't
+// looked for.
+//
+// If f isn't a supported format, an UnsupportedFormatError is returned. Once
+// settings has been set from the configuration file, or a reader, it won't be
+// updated again; subsequent calls will result in nothing being done. This is
+// the case even if r was empty or didn't have any settings: the
+// configuration file won't be looked for.
+func (s *Settings) SetFromReader(r io.Reader, f Format) error {
+	if !f.isSupported() {
+		return UnsupportedFormatError{f.String()}
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	name := "conf reader"
+	if n, ok := r.(interface{ Name() string }); ok {
+		name = "conf file " + n.Name()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.confFileVarsSet {
+		return nil
+	}
+	err = s.setFromConfSource(readerSource{s: s, name: name, format: f, b: b})
+	if err != nil {
+		return err
+	}
+	// the reader is the configuration file, even if it didn't have anything
+	s.confFileVarsSet = true
+	return nil
+}
+
+// ConfFS returns the file system that the standard settings reads the
+// configuration file from.
+func ConfFS() fs.FS { return std.ConfFS() }
+
+// SetConfFS sets the file system that the standard settings reads the
+// configuration file from. See Settings.SetConfFS.
+func SetConfFS(fsys fs.FS) { std.SetConfFS(fsys) }
+
+// SetFromReader updates the standard settings' configuration from the
+// configuration read from r, in the format f. See Settings.SetFromReader.
+func SetFromReader(r io.Reader, f Format) error { return std.SetFromReader(r, f) }
@@ -0,0 +1,144 @@
+package contour
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestConfFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.json":           {Data: []byte(`{"host": "root.example.com"}`)},
+		"conf/app.yaml":      {Data: []byte("host: conf.example.com\n")},
+		"etc/app/app.toml":   {Data: []byte(`host = "etc.example.com"`)},
+		"other/invalid.json": {Data: []byte(`{"host": `)},
+	}
+	tests := []struct {
+		fname  string
+		paths  []string
+		host   string
+		origin string
+		err    string
+	}{
+		{"app.json", nil, "root.example.com", "conf file app.json", ""},
+		{"conf/app.yaml", nil, "conf.example.com", "conf file conf/app.yaml", ""},
+		{"app.toml", []string{"conf", "etc/app"}, "etc.example.com", "conf file app.toml", ""},
+		{"app.yaml", []string{"etc/app"}, "", "", "open file app.yaml: etc/app: file does not exist"},
+		{"other/invalid.json", nil, "", "", "other/invalid.json: unexpected end of JSON input"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetConfFS(fsys)
+		tst.SetConfFilename(test.fname)
+		tst.SetConfFilePaths(test.paths)
+		tst.RegisterStringConfFileVar("host", "localhost")
+		err := tst.SetFromConfFile()
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%s: got %q; want %q", test.fname, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%s: got no error; want %q", test.fname, test.err)
+			continue
+		}
+		if v := tst.String("host"); v != test.host {
+			t.Errorf("%s: got %q; want %q", test.fname, v, test.host)
+		}
+		if o := tst.Origin("host"); o != test.origin {
+			t.Errorf("%s: origin: got %q; want %q", test.fname, o, test.origin)
+		}
+	}
+
+	// a missing file can be ok
+	tst := New("app")
+	tst.SetConfFS(fsys)
+	tst.SetConfFilename("missing.json")
+	tst.SetErrOnMissingConfFile(false)
+	tst.RegisterStringConfFileVar("host", "localhost")
+	if err := tst.Set(); err != nil {
+		t.Errorf("missing: unexpected error: %s", err)
+	}
+}
+
+func TestSetFromReader(t *testing.T) {
+	tests := []struct {
+		conf   string
+		f      Format
+		host   string
+		origin string
+		err    string
+	}{
+		{`{"host": "json.example.com"}`, JSON, "json.example.com", "conf reader", ""},
+		{"host: yaml.example.com", YAML, "yaml.example.com", "conf reader", ""},
+		{`host = "toml.example.com"`, TOML, "toml.example.com", "conf reader", ""},
+		{"", YAML, "localhost", "default", ""},
+		{"host: [", YAML, "", "", "conf reader: yaml: line 1: did not find expected node content"},
+		{`{"host": "x"}`, Unsupported, "", "", "unsupported: unsupported configuration format"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetConfFilename("app.json")
+		tst.RegisterStringConfFileVar("host", "localhost")
+		err := tst.SetFromReader(strings.NewReader(test.conf), test.f)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%q: got %q; want %q", test.conf, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%q: got no error; want %q", test.conf, test.err)
+			continue
+		}
+		if v := tst.String("host"); v != test.host {
+			t.Errorf("%q: got %q; want %q", test.conf, v, test.host)
+		}
+		if o := tst.Origin("host"); o != test.origin {
+			t.Errorf("%q: origin: got %q; want %q", test.conf, o, test.origin)
+		}
+	}
+
+	// the reader replaces the configuration file, which doesn't exist
+	tst := New("app")
+	tst.SetConfFilename("app.json")
+	tst.RegisterStringConfFileVar("host", "localhost")
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	w.WriteString(`{"host": "pipe.example.com"}`)
+	w.Close()
+	err = tst.SetFromReader(r, JSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = tst.Set()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v := tst.String("host"); v != "pipe.example.com" {
+		t.Errorf("got %q; want pipe.example.com", v)
+	}
+	if o := tst.Origin("host"); o != "conf file |0" {
+		t.Errorf("origin: got %q; want %q", o, "conf file |0")
+	}
+
+	// an empty reader still replaces the configuration file, which exists
+	tst = New("app")
+	tst.SetConfFS(fstest.MapFS{"app.json": {Data: []byte(`{"host": "file.example.com"}`)}})
+	tst.RegisterStringConfFileVar("host", "localhost")
+	err = tst.SetFromReader(strings.NewReader(""), YAML)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = tst.Set()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v := tst.String("host"); v != "localhost" {
+		t.Errorf("got %q; want localhost", v)
+	}
+}
@@ -0,0 +1,122 @@
+package contour
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The default time that a Consul blocking query waits for a change.
+const consulWaitTime = 5 * time.Minute
+
+// ConsulKV is a KVStore client for Consul's KV HTTP API, or any store that
+// serves the same API. A key's revision is its ModifyIndex and the store's
+// revision is the X-Consul-Index of the response. Watch uses blocking
+// queries, i.e. long polling.
+type ConsulKV struct {
+	// The address of the Consul agent, e.g. http://127.0.0.1:8500.
+	Addr string
+	// The ACL token, if any; it's sent in the X-Consul-Token header.
+	Token string
+	// How long a blocking query waits for a change before it's retried;
+	// 5 minutes if 0.
+	WaitTime time.Duration
+	// The client that makes the requests; http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// consulPair is a key in a Consul KV response.
+type consulPair struct {
+	Key         string
+	Value       []byte // base64 encoded in the JSON
+	ModifyIndex uint64
+}
+
+// List returns the pairs whose keys start with prefix, sorted by key, and the
+// X-Consul-Index of the response.
+func (c *ConsulKV) List(prefix string) ([]KVPair, uint64, error) {
+	return c.list(context.Background(), prefix, 0)
+}
+
+// Watch blocks until the X-Consul-Index of the keys that start with prefix is
+// different from rev, or ctx is done, and returns it.
+func (c *ConsulKV) Watch(ctx context.Context, prefix string, rev uint64) (uint64, error) {
+	for {
+		_, idx, err := c.list(ctx, prefix, rev)
+		if err != nil {
+			return rev, err
+		}
+		// a blocking query returns the same index when its wait time
+		// runs out
+		if idx != rev {
+			return idx, nil
+		}
+	}
+}
+
+// list lists the keys that start with prefix. If index isn't 0, it's a
+// blocking query that waits for the index to change.
+func (c *ConsulKV) list(ctx context.Context, prefix string, index uint64) ([]KVPair, uint64, error) {
+	q := url.Values{}
+	q.Set("recurse", "true")
+	if index > 0 {
+		wait := c.WaitTime
+		if wait == 0 {
+			wait = consulWaitTime
+		}
+		q.Set("index", strconv.FormatUint(index, 10))
+		q.Set("wait", wait.String())
+	}
+	u := strings.TrimRight(c.Addr, "/") + "/v1/kv/" + strings.TrimLeft(prefix, "/") + "?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("consul: %s", err)
+	}
+	if c.Token != "" {
+		req.Header.Set("X-Consul-Token", c.Token)
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("consul: %s", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("consul: %s", err)
+	}
+	var idx uint64
+	if h := resp.Header.Get("X-Consul-Index"); h != "" {
+		idx, err = strconv.ParseUint(h, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("consul: X-Consul-Index: %s", err)
+		}
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// there aren't any keys with the prefix
+		return nil, idx, nil
+	default:
+		return nil, 0, fmt.Errorf("consul: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var cp []consulPair
+	err = json.Unmarshal(body, &cp)
+	if err != nil {
+		return nil, 0, fmt.Errorf("consul: %s", err)
+	}
+	pairs := make([]KVPair, 0, len(cp))
+	for _, p := range cp {
+		pairs = append(pairs, KVPair{Key: p.Key, Value: p.Value, Revision: p.ModifyIndex})
+	}
+	return pairs, idx, nil
+}
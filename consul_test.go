@@ -0,0 +1,106 @@
+package contour
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// consulServer is a stand-in for Consul's KV HTTP API.
+type consulServer struct {
+	mu      sync.Mutex
+	index   uint64
+	pairs   []consulPair
+	changed chan struct{}
+}
+
+func (c *consulServer) put(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.index++
+	c.pairs = append(c.pairs, consulPair{Key: key, Value: []byte(value), ModifyIndex: c.index})
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *consulServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Consul-Token") != "secret" {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	if idx := r.URL.Query().Get("index"); idx != "" {
+		i, _ := strconv.ParseUint(idx, 10, 64)
+		wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+		c.mu.Lock()
+		index, ch := c.index, c.changed
+		c.mu.Unlock()
+		if index == i {
+			select {
+			case <-ch:
+			case <-time.After(wait):
+			}
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(c.index, 10))
+	var pairs []consulPair
+	for _, p := range c.pairs {
+		if strings.HasPrefix(p.Key, prefix) {
+			pairs = append(pairs, p)
+		}
+	}
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(pairs)
+}
+
+func TestConsulKV(t *testing.T) {
+	srv := &consulServer{changed: make(chan struct{})}
+	srv.put("app/port", "9090")
+	srv.put("other/port", "1")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	kv := &ConsulKV{Addr: ts.URL, Token: "secret", WaitTime: 10 * time.Millisecond}
+	pairs, rev, err := kv.List("app/")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(pairs, []KVPair{{Key: "app/port", Value: []byte("9090"), Revision: 1}}) || rev != 2 {
+		t.Errorf("got %v at %d; want [app/port] at 2", pairs, rev)
+	}
+	pairs, _, err = kv.List("missing/")
+	if err != nil || len(pairs) != 0 {
+		t.Errorf("missing: got %v, %v; want no pairs", pairs, err)
+	}
+
+	// the watch is retried when the wait time runs out
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		srv.put("app/host", "example.com")
+	}()
+	rev, err = kv.Watch(context.Background(), "app/", rev)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rev != 3 {
+		t.Errorf("watch: got %d; want 3", rev)
+	}
+
+	kv.Token = ""
+	_, _, err = kv.List("app/")
+	if err == nil || err.Error() != "consul: 403 Forbidden: ACL not found" {
+		t.Errorf("got %v; want consul: 403 Forbidden: ACL not found", err)
+	}
+}
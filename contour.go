@@ -17,7 +17,15 @@
 // registered with default values and are overridable according to their
 // configuration setting type. For custom override properties, e.g. can be
 // set by either a configuration file or a flag but not by an environment
-// variable, use the Register function.
+// variable, use the Register function. The order of precedence can be changed
+// with SetPrecedence, e.g. so that the configuration file overrides
+// environment variables. Other sources of configuration, e.g. a database
+// table, can be added with AddSource. Each added source has its own
+// SettingType, which SetPrecedence can place anywhere in the order of
+// precedence; by default, it's right after the configuration file. A KVSource
+// is a source whose values are in a KV store, e.g. Consul, see ConsulKV, or a
+// LocalKV; its Watch method reloads the values when they change, see
+// ReloadSource.
 //
 // Registering a configuration setting will result in settings being configured
 // to use that setting type's source, along with any lower precedence sources
@@ -39,10 +47,12 @@
 // file format that settings is set to use, which defaults to JSON. The format
 // can be set using the SetFormat method. This only needs to be done if the
 // configuration file is not explictly set using the SetConfFilename method.
-// The supported configuration formats are: JSON, TOML, and YAML. A settings
-// can also be configured to search for the configuration file until it is
-// found. Where it looks depends on how it has been configured and what
-// additional information the settings has been provided:
+// The supported configuration formats are: JSON, JSON5, TOML, YAML, HCL,
+// INI, Java .properties, and XML; the values of the last three are strings,
+// which are parsed like environment variables' values. A settings can also be
+// configured to search for the configuration file until it is found. Where it
+// looks depends on how it has been configured and what additional information
+// the settings has been provided:
 //    configuration filename
 //    paths set with SetConfFilePaths
 //    paths extracted from env vars set by SetConfFilePathEnvVars*
@@ -57,6 +67,16 @@
 // settings can be set to not return an error when the configuration file
 // cannot be found by using the SetErrOnMissingConfFile method.
 //
+// The configuration file can be read from a file system other than the
+// operating system's, e.g. an embed.FS, with SetConfFS. The configuration can
+// also be read from an io.Reader, e.g. os.Stdin, with SetFromReader. A
+// default configuration, e.g. a file embedded in the binary, can be set with
+// SetDefaultConf; it's the lowest precedence layer of the configuration.
+// Profiles, e.g. dev and prod, override the configuration file's values with
+// the profile's section, YAML document, or file; the profile is set with
+// SetProfile or by the profile flag or environment variable, see
+// SetProfileVar.
+//
 // Contour only saves the top level keys of configuration files as settings.
 // For configuration file settings that are arrays, maps, or objects, their
 // values will be saved as an interface{}.
@@ -64,9 +84,50 @@
 // Environment variables are UPPER CASE and use a NAME_KEY as the variable
 // name, where NAME is the name of the Settings, the executable name for
 // the package global Settings, and KEY is the name, or key, of the setting.
+// Settings can also be configured to read environment variables from a dotenv
+// file, .env by default; variables in the process environment take precedence
+// and the dotenv variables are never added to the process environment.
 //
 // Flags can be registered with either a short flag or alias using the short
-// parameter of Register Flag functions.
+// parameter of Register Flag functions. Flags are parsed using the flag
+// package's conventions unless settings has been set to use POSIX/GNU
+// conventions, --name and -s, with SetPOSIXFlags. The value of an interface{}
+// flag is parsed as JSON, unless the setting's value is a flag.Value, in which
+// case it sets itself. A setting can have additional short and long flag
+// names, which are also accepted as configuration file keys, with AddAlias.
+// Bool flags also have a negated flag, --no-name, that sets them to false,
+// unless there's a setting, short flag, or alias with that name. A setting can
+// be hidden from the usage and documentation with SetHidden or deprecated in
+// favor of another setting with SetDeprecated, or when it's registered, with
+// the Hide and Deprecate options. Groups of settings can be made mutually
+// exclusive, with MutuallyExclusive, or required together, with
+// RequiredTogether; the groups are checked once the settings' values are
+// final.
+//
+// Named, typed positional args can be registered with RegisterArg. They are
+// set and validated by ParseFlags and gotten like any other setting.
+//
+// With SetUseResponseFiles, ParseFlags expands @path args to the args in the
+// response file at path, which is useful when the args are too long for the
+// command line.
+//
+// SetOverrideFlag adds a repeatable flag, e.g. --set key=value, that can set
+// any configuration file, environment variable, or flag setting for a single
+// run.
+//
+// Unless a usage func is set with SetUsage, the usage is generated from the
+// registered settings; it shows how each setting can be set and where its
+// current value came from, see WriteUsage and Origin. Shell completion scripts
+// for bash, zsh, and fish are generated from the registered flags with
+// WriteCompletion or the built-in completion flag, see SetCompletionFlag. A
+// man page and a Markdown reference can be generated, e.g. by go generate,
+// with WriteManPage and WriteMarkdown.
+//
+// Settings may have commands, e.g. app serve, added with AddCommand. Each
+// command has its own settings and flags, which are set when the command is
+// run with Run. A command's environment variables are prefixed with its
+// parent's name, APP_SERVE_PORT, and its configuration file settings are in
+// the section, or table, with the command's name.
 //
 // All operations are thread-safe.
 //
@@ -83,6 +144,7 @@
 package contour
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -90,6 +152,7 @@ import (
 	"strings"
 
 	"github.com/mohae/appname"
+	"gopkg.in/yaml.v2"
 )
 
 var (
@@ -111,6 +174,21 @@ const (
 	TOML
 	// YAML encoding format
 	YAML
+	// INI encoding format. A section's keys are dotted keys, e.g. the host
+	// key in the [db] section is db.host.
+	INI
+	// Java .properties encoding format.
+	Properties
+	// HCL encoding format. A block is a nested key, e.g. the host key in the
+	// db block is the host key of the db setting's value.
+	HCL
+	// JSON5 encoding format, which includes JSONC: JSON with comments,
+	// trailing commas, unquoted keys, etc.
+	JSON5
+	// XML encoding format. The root element's children are the keys; an
+	// element with children or attributes is a nested key, whose text, if
+	// it has any, is its #text key.
+	XML
 )
 
 // Format is the type of esupported encoding for configuration files.
@@ -124,6 +202,16 @@ func (f Format) String() string {
 		return "toml"
 	case YAML:
 		return "yaml"
+	case INI:
+		return "ini"
+	case Properties:
+		return "properties"
+	case HCL:
+		return "hcl"
+	case JSON5:
+		return "json5"
+	case XML:
+		return "xml"
 	default:
 		return "unsupported"
 	}
@@ -139,10 +227,32 @@ func (f Format) isSupported() bool {
 		return true
 	case TOML:
 		return true
+	case INI:
+		return true
+	case Properties:
+		return true
+	case HCL:
+		return true
+	case JSON5:
+		return true
+	case XML:
+		return true
 	}
 	return false
 }
 
+// isTyped returns if the format has types for its values: all of the values
+// of INI, .properties, and XML are strings.
+func (f Format) isTyped() bool {
+	return f != INI && f != Properties && f != XML
+}
+
+// isFlat returns if the format's keys are flat: INI's sections and the keys
+// of .properties are dotted keys, e.g. db.host, instead of nested ones.
+func (f Format) isFlat() bool {
+	return f == INI || f == Properties
+}
+
 // ParseFormat takes a string and returns the Format it represents or an
 // UnsupportedFormatError if it can't be matched to a supported format. The
 // string is normalized to lower case before matching.
@@ -155,6 +265,16 @@ func ParseFormat(s string) (Format, error) {
 		return TOML, nil
 	case "yaml", "yml":
 		return YAML, nil
+	case "ini":
+		return INI, nil
+	case "properties", "props":
+		return Properties, nil
+	case "hcl":
+		return HCL, nil
+	case "json5", "jsonc":
+		return JSON5, nil
+	case "xml":
+		return XML, nil
 	}
 	return Unsupported, UnsupportedFormatError{s}
 }
@@ -212,6 +332,66 @@ func parseDataType(s string) dataType {
 	return _interface
 }
 
+// parseBool returns the bool value represented by v. The accepted values are
+// true, false, yes, no, on, off, 1, and 0, along with t and f for
+// compatibility with strconv.ParseBool; case is ignored. Any other value
+// results in an error.
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "true", "yes", "on", "1", "t":
+		return true, nil
+	case "false", "no", "off", "0", "f":
+		return false, nil
+	}
+	return false, errInvalidBool
+}
+
+var errInvalidBool = errors.New("invalid bool value: must be one of true, false, yes, no, on, off, 1, or 0")
+
+// parseInterface decodes v, which is either JSON or YAML, into an interface{}.
+// JSON is tried first; if v isn't valid JSON it is decoded as YAML. Mappings
+// are always returned as map[string]interface{}, regardless of the encoding.
+func parseInterface(v string) (interface{}, error) {
+	var i interface{}
+	jerr := json.Unmarshal([]byte(v), &i)
+	if jerr == nil {
+		return i, nil
+	}
+	err := yaml.Unmarshal([]byte(v), &i)
+	if err != nil {
+		// if it looks like JSON, the JSON error is more useful
+		tmp := strings.TrimSpace(v)
+		if strings.HasPrefix(tmp, "{") || strings.HasPrefix(tmp, "[") {
+			return nil, jerr
+		}
+		return nil, err
+	}
+	return stringMaps(i), nil
+}
+
+// stringMaps returns v with any map[interface{}]interface{}, the type YAML
+// decodes mappings to, converted to a map[string]interface{}. This is done
+// recursively.
+func stringMaps(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, val := range x {
+			m[fmt.Sprintf("%v", k)] = stringMaps(val)
+		}
+		return m
+	case map[string]interface{}:
+		for k, val := range x {
+			x[k] = stringMaps(val)
+		}
+	case []interface{}:
+		for i := range x {
+			x[i] = stringMaps(x[i])
+		}
+	}
+	return v
+}
+
 // DataTypeError occurs when the requested setting's data type is different
 // than the type requested.
 type DataTypeError struct {
@@ -224,9 +404,9 @@ func (e DataTypeError) Error() string {
 	return fmt.Sprintf("%s is %s, not %s", e.k, e.is, e.not)
 }
 
-// These settings are in order of precedence. Each setting type can be set by
-// any of the types with higher precedence if contour is configured to use that
-// type.
+// These settings are in the default order of precedence, see SetPrecedence.
+// Each setting type can be set by any of the types with higher precedence if
+// contour is configured to use that type.
 const (
 	// Basic settings are settings that are none of the below. These are often
 	// referred to as application settings: settings that can only be updated
@@ -245,6 +425,9 @@ const (
 	// variable, and a flag; unless it has been explicitly set to not be
 	// updateable from either a configuration file or an environment variable.
 	Flag
+	// Arg settings are set from the positional args that remain after the
+	// flags have been parsed.
+	Arg
 )
 
 // SettingType is type of setting.
@@ -262,13 +445,38 @@ func (t SettingType) String() string {
 		return "env var"
 	case Flag:
 		return "flag"
-	default:
-		return "unknown"
+	case Arg:
+		return "arg"
+	}
+	if t >= firstSourceType {
+		return "source"
 	}
+	return "unknown"
 }
 
 var ErrNoSettingName = errors.New("no setting name provided")
 
+// ParseError occurs when a value from a configuration source cannot be parsed
+// as the data type of the setting it is for. The name is the name the value
+// was provided under, e.g. the environment variable name.
+type ParseError struct {
+	typ  SettingType
+	name string
+	v    string
+	dTyp dataType
+	err  error // the underlying error, if there's more detail
+}
+
+func (e ParseError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("%s %s: cannot parse %q as %s: %s", e.typ, e.name, e.v, e.dTyp, e.err)
+	}
+	return fmt.Sprintf("%s %s: cannot parse %q as %s", e.typ, e.name, e.v, e.dTyp)
+}
+
+// Unwrap returns the underlying error, if any.
+func (e ParseError) Unwrap() error { return e.err }
+
 // SettingExistsError occurs when a setting being Added or Registered already
 // exists under the same name (k).
 type SettingExistsError struct {
@@ -94,6 +94,28 @@ var jsonResults = map[string]interface{}{
 	},
 }
 
+var json5Example = []byte(`
+// comments, unquoted keys, single quotes, and trailing commas are JSON5
+{
+	appVar1: true,
+	appVar2: false,
+	appVar3: '42',
+	appVar4: "zip",
+	appVar5: [
+		"less",
+		"sass",
+		"scss",
+	],
+	/* the logging settings */
+	logging: {
+		logging: true,
+		logcfg: "test/test.toml",
+		logfilelevel: "debug",
+		logstdoutlevel: "error",
+	},
+}
+`)
+
 var jsonTest = []byte(`
 {
 	"cfgbool": true,
@@ -592,12 +614,17 @@ func TestParseFilename(t *testing.T) {
 	}{
 		{"", Unsupported, UnsupportedFormatError{""}},
 		{"file", Unsupported, UnsupportedFormatError{""}},
-		{"file.xml", Unsupported, UnsupportedFormatError{"xml"}},
+		{"file.xml", XML, nil},
 		{"file.jpeg", Unsupported, UnsupportedFormatError{"jpeg"}},
 		{"file.json", JSON, nil},
 		{"file.jsn", JSON, nil},
 		{"file.cjson", JSON, nil},
 		{"file.cjsn", JSON, nil},
+		{"file.json5", JSON5, nil},
+		{"file.jsonc", JSON5, nil},
+		{"file.hcl", HCL, nil},
+		{"file.ini", INI, nil},
+		{"file.properties", Properties, nil},
 		{"file.toml", TOML, nil},
 		{"file.toml", TOML, nil},
 		{"file.yaml", YAML, nil},
@@ -655,3 +682,82 @@ func TestPathsFromEnvVar(t *testing.T) {
 		os.Unsetenv(test.k)
 	}
 }
+
+func TestParseBool(t *testing.T) {
+	tests := []struct {
+		v        string
+		expected bool
+		err      error
+	}{
+		{"true", true, nil},
+		{"TRUE", true, nil},
+		{"t", true, nil},
+		{"yes", true, nil},
+		{"Yes", true, nil},
+		{"on", true, nil},
+		{"ON", true, nil},
+		{"1", true, nil},
+		{" true ", true, nil},
+		{"false", false, nil},
+		{"F", false, nil},
+		{"no", false, nil},
+		{"NO", false, nil},
+		{"off", false, nil},
+		{"0", false, nil},
+		{"", false, errInvalidBool},
+		{"maybe", false, errInvalidBool},
+		{"2", false, errInvalidBool},
+		{"y", false, errInvalidBool},
+	}
+	for _, test := range tests {
+		b, err := parseBool(test.v)
+		if err != test.err {
+			t.Errorf("%q: got %v; want %v", test.v, err, test.err)
+			continue
+		}
+		if b != test.expected {
+			t.Errorf("%q: got %v; want %v", test.v, b, test.expected)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	err := ParseError{typ: EnvVar, name: "APP_DEBUG", v: "maybe", dTyp: _bool}
+	exp := `env var APP_DEBUG: cannot parse "maybe" as bool`
+	if err.Error() != exp {
+		t.Errorf("got %s; want %s", err.Error(), exp)
+	}
+}
+
+func TestParseInterface(t *testing.T) {
+	tests := []struct {
+		v        string
+		expected interface{}
+		err      string
+	}{
+		{`{"cpu": 2}`, map[string]interface{}{"cpu": float64(2)}, ""},
+		{`[1, "a", true]`, []interface{}{float64(1), "a", true}, ""},
+		{`"quoted"`, "quoted", ""},
+		{`{cpu: 2, mem: {max: 4}}`, map[string]interface{}{"cpu": 2, "mem": map[string]interface{}{"max": 4}}, ""},
+		{"cpu: 2\nnames:\n  - a\n  - b", map[string]interface{}{"cpu": 2, "names": []interface{}{"a", "b"}}, ""},
+		{`plain`, "plain", ""},
+		{`{"cpu":`, nil, "unexpected end of JSON input"},
+		{`[1, 2`, nil, "unexpected end of JSON input"},
+	}
+	for _, test := range tests {
+		v, err := parseInterface(test.v)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%q: got %q; want %q", test.v, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%q: got no error; want %q", test.v, test.err)
+			continue
+		}
+		if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("%q: got %#v; want %#v", test.v, v, test.expected)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package contour
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The origin of the values that were set by the default configuration.
+const defaultConfOrigin = "default conf"
+
+// SetDefaultConf sets settings' default configuration to b, in the format f,
+// e.g. a file that's embedded in the binary with a go:embed directive. It's
+// the lowest precedence layer of settings' configuration: before settings is
+// set from any of its sources, e.g. the configuration file, its values replace
+// the registered values of its settings, and they're what the sources
+// override. The origin of its values is "default conf".
+//
+// Like the configuration file, its keys are the names, or aliases, of
+// configuration settings, which must be registered before settings is set. A
+// key that isn't a setting results in a SettingNotFoundError when settings is
+// set; a Core or Basic setting, a CoreUpdateError or an UpdateError.
+//
+// If f isn't a supported format, an UnsupportedFormatError is returned; if b
+// cannot be parsed, the parse error. If settings has already been set from any
+// of its sources, an ErrSourcesSet is returned.
+func (s *Settings) SetDefaultConf(b []byte, f Format) error {
+	if !f.isSupported() {
+		return UnsupportedFormatError{f.String()}
+	}
+	_, err := unmarshalConfBytes(f, b)
+	if err != nil {
+		return fmt.Errorf("%s: %s", defaultConfOrigin, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.confFileVarsSet || s.envVarsSet || s.flagsParsed {
+		return ErrSourcesSet
+	}
+	s.defaultConf = b
+	s.defaultConfFormat = f
+	s.defaultConfSet = false
+	return nil
+}
+
+// setDefaultConf sets settings' default configuration's values, if it has one
+// and they haven't been set. This assumes the lock has been obtained.
+func (s *Settings) setDefaultConf() error {
+	if s.defaultConf == nil || s.defaultConfSet {
+		return nil
+	}
+	vals, err := s.confValues(defaultConfOrigin, defaultConfOrigin, s.defaultConfFormat, s.defaultConf)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, ok := s.settings[k]
+		if !ok {
+			return fmt.Errorf("%s: %s", defaultConfOrigin, SettingNotFoundError{k: k})
+		}
+		if v.IsCore {
+			return fmt.Errorf("%s: %s", defaultConfOrigin, CoreUpdateError{k: k})
+		}
+		if !v.IsConfFileVar && !v.IsEnvVar && !v.IsFlag {
+			return fmt.Errorf("%s: %s", defaultConfOrigin, UpdateError{typ: Basic.String(), k: k})
+		}
+		// a value that was set by a source isn't a default
+		if v.SetBy != 0 {
+			continue
+		}
+		v.Value = vals[k].Value
+		v.Origin = defaultConfOrigin
+		s.settings[k] = v
+	}
+	s.defaultConfSet = true
+	return nil
+}
+
+// SetDefaultConf sets the standard settings' default configuration to b, in
+// the format f. See Settings.SetDefaultConf.
+func SetDefaultConf(b []byte, f Format) error { return std.SetDefaultConf(b, f) }
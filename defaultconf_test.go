@@ -0,0 +1,127 @@
+package contour
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetDefaultConf(t *testing.T) {
+	tests := []struct {
+		conf string
+		f    Format
+		err  string
+	}{
+		{"port: 9090", YAML, ""},
+		{`{"port": 9090}`, JSON, ""},
+		{"port: [", YAML, "default conf: yaml: line 1: did not find expected node content"},
+		{"port: 9090", Unsupported, "unsupported: unsupported configuration format"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		err := tst.SetDefaultConf([]byte(test.conf), test.f)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%q: got %q; want %q", test.conf, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%q: got no error; want %q", test.conf, test.err)
+		}
+	}
+
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.RegisterIntEnvVar("port", 8080)
+	tst.Set()
+	if err := tst.SetDefaultConf([]byte("port: 9090"), YAML); err != ErrSourcesSet {
+		t.Errorf("got %v; want %v", err, ErrSourcesSet)
+	}
+}
+
+func TestDefaultConf(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "app.yaml")
+	err = ioutil.WriteFile(fname, []byte("host: conf.example.com\n"), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	os.Setenv("APP_LOG", "debug")
+	defer os.Unsetenv("APP_LOG")
+	defaults := []byte(`{"host": "default.example.com", "port": 9090, "log": "info", "tls": "yes", "verbose": true}`)
+	tests := []struct {
+		fname   string
+		args    []string
+		port    int
+		values  map[string]interface{}
+		origins map[string]string
+	}{
+		{
+			"", nil, 9090,
+			map[string]interface{}{"host": "default.example.com", "log": "debug", "tls": true, "verbose": true},
+			map[string]string{"host": "default conf", "port": "default conf", "log": "env var APP_LOG"},
+		},
+		{
+			fname, []string{"-port", "7070"}, 7070,
+			map[string]interface{}{"host": "conf.example.com", "log": "debug", "tls": true, "verbose": true},
+			map[string]string{"host": "conf file " + fname, "port": "flag -port", "tls": "default conf"},
+		},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetUsage(func() {})
+		tst.SetErrOnMissingConfFile(false)
+		if test.fname != "" {
+			tst.SetConfFilename(test.fname)
+		}
+		tst.RegisterStringConfFileVar("host", "localhost")
+		tst.RegisterIntFlag("port", "", 0, "", "")
+		tst.RegisterBoolFlag("verbose", "v", false, "", "")
+		tst.RegisterStringEnvVar("log", "")
+		tst.RegisterBoolConfFileVar("tls", false)
+		err = tst.SetDefaultConf(defaults, JSON)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		err = tst.Set()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.fname, err)
+			continue
+		}
+		_, err = tst.ParseFlags(test.args)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.fname, err)
+			continue
+		}
+		if v := tst.Int("port"); v != test.port {
+			t.Errorf("%s: port: got %d; want %d", test.fname, v, test.port)
+		}
+		for k, v := range test.values {
+			if got := tst.Get(k); got != v {
+				t.Errorf("%s: %s: got %#v; want %#v", test.fname, k, got, v)
+			}
+		}
+		for k, o := range test.origins {
+			if got := tst.Origin(k); got != o {
+				t.Errorf("%s: %s origin: got %q; want %q", test.fname, k, got, o)
+			}
+		}
+	}
+
+	// the default configuration's keys must be configuration settings
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.RegisterStringConfFileVar("host", "localhost")
+	tst.AddString("name", "app")
+	tst.SetDefaultConf([]byte("name: other"), YAML)
+	err = tst.Set()
+	if err == nil || err.Error() != "setting configuration from file failed: default conf: name: basic settings cannot be updated" {
+		t.Errorf("got %v; want basic settings cannot be updated", err)
+	}
+}
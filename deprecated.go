@@ -0,0 +1,239 @@
+package contour
+
+import (
+	"flag"
+	"fmt"
+)
+
+// RegisterOption is an option of an environment variable or flag setting's
+// registration, e.g. Hide or Deprecate.
+type RegisterOption func(*registerOptions)
+
+// registerOptions are the options a setting was registered with.
+type registerOptions struct {
+	hidden        bool
+	deprecatedFor string
+}
+
+// Hide is a RegisterOption that hides the setting, like SetHidden.
+func Hide() RegisterOption {
+	return func(o *registerOptions) { o.hidden = true }
+}
+
+// Deprecate is a RegisterOption that marks the setting as deprecated and
+// replaced by setting r, like SetDeprecated. The replacement must be
+// registered first.
+func Deprecate(r string) RegisterOption {
+	return func(o *registerOptions) { o.deprecatedFor = r }
+}
+
+// registerOptions returns the options in opts for setting k, of the data type
+// typ, that's being registered. If the options are invalid, e.g. k is
+// deprecated for a setting that doesn't exist, an error is returned, like
+// SetDeprecated's. This assumes the lock has been obtained.
+func (s *Settings) registerOptions(typ dataType, k string, opts []RegisterOption) (registerOptions, error) {
+	var o registerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.deprecatedFor == "" {
+		return o, nil
+	}
+	return o, s.checkDeprecated(typ, k, o.deprecatedFor)
+}
+
+// setRegisterOptions sets the options o of setting k, which has just been
+// registered. This assumes the lock has been obtained.
+func (s *Settings) setRegisterOptions(k string, o registerOptions) {
+	v := s.settings[k]
+	v.Hidden = o.hidden
+	v.DeprecatedFor = o.deprecatedFor
+	s.settings[k] = v
+}
+
+// Hidden returns if setting k is hidden; false is returned if k doesn't exist
+// in settings.
+func (s *Settings) Hidden(k string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings[k].Hidden
+}
+
+// SetHidden sets if setting k is hidden. A hidden setting isn't included in
+// the usage, completion scripts, or generated documentation; it can still be
+// set in all of the ways it was registered to be. A setting can also be hidden
+// when it's registered, see Hide. A SettingNotFoundError will be returned if k
+// doesn't exist in settings.
+func (s *Settings) SetHidden(k string, b bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.settings[k]
+	if !ok {
+		return SettingNotFoundError{k: k}
+	}
+	v.Hidden = b
+	s.settings[k] = v
+	return nil
+}
+
+// DeprecatedFor returns the setting that replaces setting k, if k is
+// deprecated; an empty string is returned if it isn't or if k doesn't exist in
+// settings.
+func (s *Settings) DeprecatedFor(k string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings[k].DeprecatedFor
+}
+
+// SetDeprecated marks setting k as deprecated and replaced by setting r; k and
+// r must have the same data type. When k is set by its flag or environment
+// variable, a warning is emitted, see SetWarnFunc, and the value is forwarded
+// to r, if r can be set the same way and hasn't been set by its own flag or
+// environment variable. Deprecated settings are shown as such in the usage
+// and generated documentation; use SetHidden to hide them. A setting can also
+// be deprecated when it's registered, see Deprecate.
+//
+// A SettingNotFoundError will be returned if either k or r doesn't exist in
+// settings.
+func (s *Settings) SetDeprecated(k, r string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.settings[k]
+	if !ok {
+		return SettingNotFoundError{k: k}
+	}
+	err := s.checkDeprecated(v.Type, k, r)
+	if err != nil {
+		return err
+	}
+	v.DeprecatedFor = r
+	s.settings[k] = v
+	return nil
+}
+
+// checkDeprecated returns an error if setting k, of the data type typ, can't
+// be deprecated for setting r. This assumes the lock has been obtained.
+func (s *Settings) checkDeprecated(typ dataType, k, r string) error {
+	rv, ok := s.settings[r]
+	if !ok {
+		return SettingNotFoundError{k: r}
+	}
+	if k == r {
+		return fmt.Errorf("%s: cannot be deprecated for itself", k)
+	}
+	if typ != rv.Type {
+		return fmt.Errorf("%s: cannot be deprecated for %s: %s is not a %s", k, r, r, typ)
+	}
+	return nil
+}
+
+// SetWarnFunc sets the func that settings' warnings, e.g. that a deprecated
+// flag was used, are passed to. By default, warnings are written to os.Stderr.
+// If f is nil, warnings are discarded. The func is called while settings is
+// locked so it must not use settings.
+func (s *Settings) SetWarnFunc(f func(msg string)) {
+	s.mu.Lock()
+	s.warnFunc = f
+	s.warnSet = true
+	s.mu.Unlock()
+}
+
+// warn emits a warning. This assumes the lock has been obtained.
+func (s *Settings) warn(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if !s.warnSet {
+		fmt.Fprintf(s.flagSet.Output(), "%s: warning: %s\n", s.name, msg)
+		return
+	}
+	if s.warnFunc != nil {
+		s.warnFunc(msg)
+	}
+}
+
+// forwardDeprecatedFlags warns about each deprecated flag that was parsed and
+// forwards its value to its replacement's flag, unless the replacement's flag
+// was also parsed. This assumes the lock has been obtained and the flags have
+// been visited.
+func (s *Settings) forwardDeprecatedFlags() error {
+	parsed := append([]string(nil), s.parsedFlags...)
+	for _, k := range parsed {
+		v := s.settings[k]
+		if v.DeprecatedFor == "" {
+			continue
+		}
+		r := s.settings[v.DeprecatedFor]
+		// a replacement without a flag, e.g. an interface{} setting, can't
+		// be forwarded to
+		var rf *flag.Flag
+		if r.IsFlag {
+			rf = s.flagSet.Lookup(r.Name)
+		}
+		if rf == nil {
+			s.warn("flag %s is deprecated", s.longFlag(k))
+			continue
+		}
+		s.warn("flag %s is deprecated, use %s", s.longFlag(k), s.longFlag(r.Name))
+		if s.WasVisited(r.Name) || s.overridden(r, Flag) {
+			continue
+		}
+		err := rf.Value.Set(s.flagSet.Lookup(k).Value.String())
+		if err != nil {
+			return fmt.Errorf("%s: forward deprecated flag %s: %s", r.Name, k, err)
+		}
+		s.visited(rf)
+		r = s.settings[r.Name]
+		r.Origin = v.Origin
+		s.settings[r.Name] = r
+	}
+	return nil
+}
+
+// forwardDeprecatedEnvVars warns about each deprecated setting that was set by
+// its environment variable and forwards its value to its replacement, unless
+// the replacement's environment variable was also set. This assumes the lock
+// has been obtained and the environment variables have been processed.
+func (s *Settings) forwardDeprecatedEnvVars() error {
+	for k, v := range s.settings {
+		if v.DeprecatedFor == "" || !v.IsEnvVar || s.getenv(s.EnvVarName(k)) == "" {
+			continue
+		}
+		r := s.settings[v.DeprecatedFor]
+		if !r.IsEnvVar {
+			s.warn("env var %s is deprecated", s.EnvVarName(k))
+			continue
+		}
+		s.warn("env var %s is deprecated, use %s", s.EnvVarName(k), s.EnvVarName(r.Name))
+		if s.getenv(s.EnvVarName(r.Name)) != "" {
+			continue
+		}
+		err := s.update(EnvVar, r.Name, v.Value)
+		if err != nil {
+			return fmt.Errorf("%s: forward deprecated env var %s: %s", r.Name, s.EnvVarName(k), err)
+		}
+		r = s.settings[r.Name]
+		r.Origin = v.Origin
+		s.settings[r.Name] = r
+	}
+	return nil
+}
+
+// Hidden returns if the standard settings' setting k is hidden.
+func Hidden(k string) bool { return std.Hidden(k) }
+
+// SetHidden sets if the standard settings' setting k is hidden from the
+// usage, completion scripts, and generated documentation. A
+// SettingNotFoundError will be returned if k doesn't exist.
+func SetHidden(k string, b bool) error { return std.SetHidden(k, b) }
+
+// DeprecatedFor returns the setting that replaces the standard settings'
+// setting k, if k is deprecated.
+func DeprecatedFor(k string) string { return std.DeprecatedFor(k) }
+
+// SetDeprecated marks the standard settings' setting k as deprecated and
+// replaced by setting r. A SettingNotFoundError will be returned if either k
+// or r doesn't exist.
+func SetDeprecated(k, r string) error { return std.SetDeprecated(k, r) }
+
+// SetWarnFunc sets the func that the standard settings' warnings are passed
+// to. If f is nil, warnings are discarded.
+func SetWarnFunc(f func(msg string)) { std.SetWarnFunc(f) }
@@ -0,0 +1,206 @@
+package contour
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newDeprecatedSettings(warnings *[]string) *Settings {
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.SetPOSIXFlags(true)
+	tst.SetUsage(func() {})
+	tst.SetWarnFunc(func(msg string) { *warnings = append(*warnings, msg) })
+	tst.RegisterIntFlag("port", "p", 8080, "8080", "port to listen on")
+	tst.RegisterIntFlag("listen-port", "", 8080, "8080", "deprecated: use port", Deprecate("port"))
+	tst.RegisterStringFlag("debug-token", "", "", "", "internal", Hide())
+	return tst
+}
+
+func TestDeprecatedFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		env      map[string]string
+		expected int
+		origin   string
+		warnings []string
+	}{
+		{nil, nil, 8080, "default", nil},
+		{[]string{"--listen-port", "9090"}, nil, 9090, "flag --listen-port", []string{"flag --listen-port is deprecated, use --port"}},
+		{[]string{"--listen-port", "9090", "-p", "7070"}, nil, 7070, "flag -p", []string{"flag --listen-port is deprecated, use --port"}},
+		{nil, map[string]string{"APP_LISTEN-PORT": "9090"}, 9090, "env var APP_LISTEN-PORT", []string{"env var APP_LISTEN-PORT is deprecated, use APP_PORT"}},
+		{nil, map[string]string{"APP_LISTEN-PORT": "9090", "APP_PORT": "7070"}, 7070, "env var APP_PORT", []string{"env var APP_LISTEN-PORT is deprecated, use APP_PORT"}},
+		{[]string{"--port", "6060"}, map[string]string{"APP_LISTEN-PORT": "9090"}, 6060, "flag --port", []string{"env var APP_LISTEN-PORT is deprecated, use APP_PORT"}},
+	}
+	for _, test := range tests {
+		for k, v := range test.env {
+			os.Setenv(k, v)
+		}
+		var warnings []string
+		tst := newDeprecatedSettings(&warnings)
+		err := tst.Set()
+		if err != nil {
+			t.Errorf("%v: unexpected error: %s", test.args, err)
+		}
+		_, err = tst.ParseFlags(test.args)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %s", test.args, err)
+		}
+		for k := range test.env {
+			os.Unsetenv(k)
+		}
+		if v := tst.Int("port"); v != test.expected {
+			t.Errorf("%v %v: got %d; want %d", test.args, test.env, v, test.expected)
+		}
+		if o := tst.Origin("port"); o != test.origin {
+			t.Errorf("%v %v: origin: got %q; want %q", test.args, test.env, o, test.origin)
+		}
+		if !reflect.DeepEqual(warnings, test.warnings) {
+			t.Errorf("%v %v: warnings: got %q; want %q", test.args, test.env, warnings, test.warnings)
+		}
+	}
+}
+
+func TestDeprecatedFlagWithoutReplacementFlag(t *testing.T) {
+	var warnings []string
+	tst := newDeprecatedSettings(&warnings)
+	tst.RegisterSetting("interface", "labels", "", nil, "", "", false, true, true, true)
+	// SetDeprecated requires the same data type; what matters here is that
+	// the replacement isn't in the flag set.
+	v := tst.settings["debug-token"]
+	v.DeprecatedFor = "labels"
+	tst.settings["debug-token"] = v
+	tst.flagSet.String("debug-token", "x", "")
+	tst.parsedFlags = []string{"debug-token"}
+	err := tst.forwardDeprecatedFlags()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{"flag --debug-token is deprecated"}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings: got %q; want %q", warnings, want)
+	}
+	if v := tst.Get("labels"); v != nil {
+		t.Errorf("labels: got %v; want nil", v)
+	}
+}
+
+func TestSetDeprecated(t *testing.T) {
+	tst := New("app")
+	tst.RegisterIntFlag("port", "p", 8080, "8080", "")
+	tst.RegisterStringFlag("host", "", "", "", "")
+	tests := []struct {
+		k, r string
+		err  string
+	}{
+		{"nope", "port", "nope: setting not found"},
+		{"port", "nope", "nope: setting not found"},
+		{"port", "port", "port: cannot be deprecated for itself"},
+		{"host", "port", "host: cannot be deprecated for port: port is not a string"},
+	}
+	for _, test := range tests {
+		err := tst.SetDeprecated(test.k, test.r)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s, %s: got %v; want %q", test.k, test.r, err, test.err)
+		}
+	}
+	err := tst.SetHidden("nope", true)
+	if err != (SettingNotFoundError{k: "nope"}) {
+		t.Errorf("got %v; want %s", err, SettingNotFoundError{k: "nope"})
+	}
+}
+
+func TestRegisterOptions(t *testing.T) {
+	tests := []struct {
+		k      string
+		opts   []RegisterOption
+		hidden bool
+		r      string
+		err    string
+	}{
+		{"a", nil, false, "", ""},
+		{"b", []RegisterOption{Hide()}, true, "", ""},
+		{"c", []RegisterOption{Deprecate("port")}, false, "port", ""},
+		{"d", []RegisterOption{Hide(), Deprecate("port")}, true, "port", ""},
+		{"e", []RegisterOption{Deprecate("nope")}, false, "", "nope: setting not found"},
+		{"f", []RegisterOption{Deprecate("f")}, false, "", "f: setting not found"},
+		{"port", []RegisterOption{Deprecate("port")}, false, "", "port: cannot be deprecated for itself"},
+		{"g", []RegisterOption{Deprecate("host")}, false, "", "g: cannot be deprecated for host: host is not a int"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.RegisterIntFlag("port", "p", 8080, "8080", "")
+		tst.RegisterStringEnvVar("host", "")
+		var err error
+		if test.k == "port" {
+			// already registered
+			err = tst.SetDeprecated(test.k, "port")
+		} else {
+			err = tst.RegisterIntFlag(test.k, "", 0, "0", "", test.opts...)
+		}
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%s: got %q; want %q", test.k, err, test.err)
+			}
+			// the setting isn't registered if an option is invalid
+			if test.k != "port" && tst.Exists(test.k) {
+				t.Errorf("%s: registered with invalid options", test.k)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%s: got no error; want %q", test.k, test.err)
+			continue
+		}
+		if v := tst.Hidden(test.k); v != test.hidden {
+			t.Errorf("%s: hidden: got %v; want %v", test.k, v, test.hidden)
+		}
+		if v := tst.DeprecatedFor(test.k); v != test.r {
+			t.Errorf("%s: deprecated for: got %q; want %q", test.k, v, test.r)
+		}
+	}
+	// env vars take the options too
+	tst := New("app")
+	tst.RegisterStringEnvVar("host", "")
+	err := tst.RegisterStringEnvVar("addr", "", Hide(), Deprecate("host"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !tst.Hidden("addr") || tst.DeprecatedFor("addr") != "host" {
+		t.Errorf("addr: got hidden %v, deprecated for %q; want true, host", tst.Hidden("addr"), tst.DeprecatedFor("addr"))
+	}
+}
+
+func TestHiddenAndDeprecatedUsage(t *testing.T) {
+	var warnings []string
+	tst := newDeprecatedSettings(&warnings)
+	var buf bytes.Buffer
+	tst.WriteUsage(&buf)
+	if strings.Contains(buf.String(), "debug-token") {
+		t.Errorf("usage: expected hidden flag to not be shown; got\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "deprecated: use port") {
+		t.Errorf("usage: expected the flag to be shown as deprecated; got\n%s", buf.String())
+	}
+	buf.Reset()
+	tst.WriteCompletion(&buf, "bash")
+	if strings.Contains(buf.String(), "debug-token") {
+		t.Errorf("completion: expected hidden flag to not be included; got\n%s", buf.String())
+	}
+	buf.Reset()
+	tst.WriteMarkdown(&buf)
+	if strings.Contains(buf.String(), "debug-token") {
+		t.Errorf("markdown: expected hidden flag to not be included; got\n%s", buf.String())
+	}
+	// hidden flags can still be used
+	_, err := tst.ParseFlags([]string{"--debug-token", "x"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tst.String("debug-token") != "x" {
+		t.Errorf("got %q; want \"x\"", tst.String("debug-token"))
+	}
+}
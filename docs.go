@@ -0,0 +1,311 @@
+package contour
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// docSettings returns settings' flags, environment variables, and
+// configuration file settings, each sorted by name. A setting is in each group
+// that it can be set by, e.g. a flag setting is usually in all three. This
+// assumes the lock has been obtained.
+func (s *Settings) docSettings() (flags, envVars, confVars []setting) {
+	for _, v := range s.settings {
+		if v.IsCore || v.Hidden {
+			continue
+		}
+		if v.IsFlag {
+			flags = append(flags, v)
+		}
+		if v.IsEnvVar {
+			envVars = append(envVars, v)
+		}
+		if v.IsConfFileVar {
+			confVars = append(confVars, v)
+		}
+	}
+	return sortSettings(flags), sortSettings(envVars), sortSettings(confVars)
+}
+
+// WriteManPage writes a man page, in roff, for settings to w. The man page is
+// generated from the registered settings and documents settings' commands,
+// args, flags, environment variables, and configuration file settings,
+// including their types, defaults, choices, and usage. The page's name is
+// settings' name and its description, if set, is used for the NAME and
+// DESCRIPTION sections; see SetDescription.
+//
+// The output doesn't depend on the environment, e.g. there is no date, so
+// that it can be produced by go generate and committed.
+func (s *Settings) WriteManPage(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var buf bytes.Buffer
+	flags, envVars, confVars := s.docSettings()
+
+	fmt.Fprintf(&buf, ".TH \"%s\" 1\n", roffEscape(strings.ToUpper(s.name)))
+	buf.WriteString(".SH NAME\n")
+	buf.WriteString(roffEscape(s.name))
+	if s.description != "" {
+		buf.WriteString(` \- ` + roffEscape(s.description))
+	}
+	buf.WriteString("\n.SH SYNOPSIS\n")
+	fmt.Fprintf(&buf, ".B %s\n", roffEscape(s.flagSet.Name()))
+	if len(flags) > 0 {
+		buf.WriteString(`[\fIflags\fR]` + "\n")
+	}
+	for _, k := range s.args {
+		fmt.Fprintf(&buf, "\\fI%s\\fR\n", roffEscape(argForm(s.settings[k])))
+	}
+	if len(s.commands) > 0 {
+		buf.WriteString(`\fIcommand\fR [\fIargs\fR]` + "\n")
+	}
+	if s.description != "" {
+		buf.WriteString(".SH DESCRIPTION\n")
+		buf.WriteString(roffEscape(s.description) + "\n")
+	}
+	if len(s.commands) > 0 {
+		buf.WriteString(".SH COMMANDS\n")
+		for _, k := range sortedCommands(s.commands) {
+			fmt.Fprintf(&buf, ".TP\n.B %s\n%s\n", roffEscape(k), roffEscape(s.commands[k].usage))
+		}
+	}
+	if len(s.args) > 0 {
+		buf.WriteString(".SH ARGUMENTS\n")
+		for _, k := range s.args {
+			v := s.settings[k]
+			fmt.Fprintf(&buf, ".TP\n\\fI%s\\fR %s\n", roffEscape(argForm(v)), usageType(v))
+			s.writeManDesc(&buf, v, nil)
+		}
+	}
+	if len(flags) > 0 {
+		buf.WriteString(".SH OPTIONS\n")
+		for _, v := range flags {
+			buf.WriteString(".TP\n")
+			if v.Short != "" {
+				fmt.Fprintf(&buf, `\fB%s\fR, `, roffEscape("-"+v.Short))
+			}
+			fmt.Fprintf(&buf, `\fB%s\fR`, roffEscape(s.flagName(v)))
+			if v.Type != _bool {
+				fmt.Fprintf(&buf, ` \fI%s\fR`, usageType(v))
+			}
+			buf.WriteString("\n")
+			var info []string
+			if len(v.Alias) > 0 {
+				info = append(info, "Aliases: "+strings.Join(s.aliasFlags(v), ", "))
+			}
+			if v.IsEnvVar {
+				info = append(info, "Environment variable: "+s.EnvVarName(v.Name))
+			}
+			if v.IsConfFileVar {
+				info = append(info, "Configuration file key: "+s.confKey(v.Name))
+			}
+			s.writeManDesc(&buf, v, info)
+		}
+	}
+	if len(envVars) > 0 {
+		buf.WriteString(".SH ENVIRONMENT\n")
+		for _, v := range envVars {
+			fmt.Fprintf(&buf, ".TP\n.B %s\n", roffEscape(s.EnvVarName(v.Name)))
+			info := []string{"Type: " + usageType(v)}
+			if v.IsFlag {
+				info = append(info, "Flag: "+s.longFlag(v.Name))
+			}
+			s.writeManDesc(&buf, v, info)
+		}
+	}
+	if len(confVars) > 0 {
+		buf.WriteString(".SH CONFIGURATION\n")
+		if s.useConfFile {
+			fmt.Fprintf(&buf, "Settings read from the configuration file, \\fI%s\\fR.\n", roffEscape(s.docConfFilename()))
+		}
+		for _, v := range confVars {
+			fmt.Fprintf(&buf, ".TP\n.B %s\n", roffEscape(s.confKey(v.Name)))
+			info := []string{"Type: " + usageType(v)}
+			if len(v.Alias) > 0 {
+				info = append(info, "Aliases: "+strings.Join(v.Alias, ", "))
+			}
+			s.writeManDesc(&buf, v, info)
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeManDesc writes setting v's usage, default, choices, and the additional
+// info, each on its own line. This assumes the lock has been obtained.
+func (s *Settings) writeManDesc(buf *bytes.Buffer, v setting, info []string) {
+	var lines []string
+	if v.Usage != "" {
+		lines = append(lines, v.Usage)
+	}
+	if v.Default != "" {
+		lines = append(lines, "Default: "+v.Default)
+	}
+	if len(v.Choices) > 0 {
+		lines = append(lines, "Choices: "+strings.Join(v.Choices, ", "))
+	}
+	if v.DeprecatedFor != "" {
+		lines = append(lines, "Deprecated: use "+v.DeprecatedFor)
+	}
+	lines = append(lines, info...)
+	for i, l := range lines {
+		if i > 0 {
+			buf.WriteString(".br\n")
+		}
+		buf.WriteString(roffEscape(l) + "\n")
+	}
+}
+
+// docConfFilename returns the configuration filename used in the
+// documentation. This assumes the lock has been obtained.
+func (s *Settings) docConfFilename() string {
+	if s.confFilename != "" {
+		return s.confFilename
+	}
+	return s.name + "." + s.format.String()
+}
+
+// roffEscape escapes v for use as roff text: backslashes and dashes are
+// escaped and a leading . or ' is prevented from being read as a request.
+func roffEscape(v string) string {
+	v = strings.NewReplacer(`\`, `\e`, `-`, `\-`).Replace(v)
+	if strings.HasPrefix(v, ".") || strings.HasPrefix(v, "'") {
+		v = `\&` + v
+	}
+	return v
+}
+
+// WriteMarkdown writes a Markdown reference for settings to w. The reference
+// is generated from the registered settings and documents settings' commands,
+// args, flags, environment variables, and configuration file settings,
+// including their types, defaults, choices, and usage, in tables. Like
+// WriteManPage, the output doesn't depend on the environment so that it can
+// be produced by go generate.
+func (s *Settings) WriteMarkdown(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var buf bytes.Buffer
+	flags, envVars, confVars := s.docSettings()
+
+	fmt.Fprintf(&buf, "# %s\n\n", s.name)
+	if s.description != "" {
+		buf.WriteString(s.description + "\n\n")
+	}
+	buf.WriteString("## Usage\n\n")
+	fmt.Fprintf(&buf, "    %s\n", s.synopsis(len(flags) > 0))
+	if len(s.commands) > 0 {
+		buf.WriteString("\n## Commands\n\n")
+		rows := [][]string{{"Command", "Description"}}
+		for _, k := range sortedCommands(s.commands) {
+			rows = append(rows, []string{mdCode(k), mdCell(s.commands[k].usage)})
+		}
+		writeMarkdownTable(&buf, rows)
+	}
+	if len(s.args) > 0 {
+		buf.WriteString("\n## Arguments\n\n")
+		rows := [][]string{{"Argument", "Type", "Required", "Description"}}
+		for _, k := range s.args {
+			v := s.settings[k]
+			required := "no"
+			if v.Required {
+				required = "yes"
+			}
+			rows = append(rows, []string{mdCode(argForm(v)), usageType(v), required, mdDesc(v)})
+		}
+		writeMarkdownTable(&buf, rows)
+	}
+	if len(flags) > 0 {
+		buf.WriteString("\n## Flags\n\n")
+		rows := [][]string{{"Flag", "Short", "Type", "Default", "Environment variable", "Configuration key", "Description"}}
+		for _, v := range flags {
+			var short, env, conf string
+			if v.Short != "" {
+				short = mdCode("-" + v.Short)
+			}
+			if v.IsEnvVar {
+				env = mdCode(s.EnvVarName(v.Name))
+			}
+			if v.IsConfFileVar {
+				conf = mdCode(s.confKey(v.Name))
+			}
+			name := mdCode(s.flagName(v))
+			for _, a := range s.aliasFlags(v) {
+				name += ", " + mdCode(a)
+			}
+			rows = append(rows, []string{name, short, usageType(v), mdCode(v.Default), env, conf, mdDesc(v)})
+		}
+		writeMarkdownTable(&buf, rows)
+	}
+	if len(envVars) > 0 {
+		buf.WriteString("\n## Environment variables\n\n")
+		rows := [][]string{{"Variable", "Type", "Default", "Description"}}
+		for _, v := range envVars {
+			rows = append(rows, []string{mdCode(s.EnvVarName(v.Name)), usageType(v), mdCode(v.Default), mdDesc(v)})
+		}
+		writeMarkdownTable(&buf, rows)
+	}
+	if len(confVars) > 0 {
+		buf.WriteString("\n## Configuration file\n\n")
+		if s.useConfFile {
+			fmt.Fprintf(&buf, "Settings read from the configuration file, %s.\n\n", mdCode(s.docConfFilename()))
+		}
+		rows := [][]string{{"Key", "Type", "Default", "Description"}}
+		for _, v := range confVars {
+			rows = append(rows, []string{mdCode(s.confKey(v.Name)), usageType(v), mdCode(v.Default), mdDesc(v)})
+		}
+		writeMarkdownTable(&buf, rows)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeMarkdownTable writes rows as a Markdown table; the first row is the
+// header.
+func writeMarkdownTable(buf *bytes.Buffer, rows [][]string) {
+	for i, row := range rows {
+		buf.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			buf.WriteString(strings.Repeat("| --- ", len(row)) + "|\n")
+		}
+	}
+}
+
+// mdDesc returns setting v's usage, with its choices, for a Markdown table.
+func mdDesc(v setting) string {
+	d := mdCell(v.Usage)
+	if len(v.Choices) > 0 {
+		choices := make([]string, len(v.Choices))
+		for i, c := range v.Choices {
+			choices[i] = mdCode(c)
+		}
+		d = strings.TrimSpace(d + " (one of: " + strings.Join(choices, ", ") + ")")
+	}
+	if v.DeprecatedFor != "" {
+		d = strings.TrimSpace(d + " Deprecated: use " + mdCode(v.DeprecatedFor) + ".")
+	}
+	return d
+}
+
+// mdCode returns v as inline code for a Markdown table; an empty v is left
+// empty.
+func mdCode(v string) string {
+	if v == "" {
+		return ""
+	}
+	return "`" + strings.Replace(v, "|", `\|`, -1) + "`"
+}
+
+// mdCell escapes v for use in a Markdown table cell.
+func mdCell(v string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(v)
+}
+
+// WriteManPage writes a man page, in roff, for the standard settings to w.
+// See Settings.WriteManPage.
+func WriteManPage(w io.Writer) error { return std.WriteManPage(w) }
+
+// WriteMarkdown writes a Markdown reference for the standard settings to w.
+// See Settings.WriteMarkdown.
+func WriteMarkdown(w io.Writer) error { return std.WriteMarkdown(w) }
@@ -0,0 +1,160 @@
+package contour
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newDocSettings() *Settings {
+	tst := New("app")
+	tst.SetDescription("serves things")
+	tst.SetPOSIXFlags(true)
+	tst.RegisterBoolFlag("verbose", "v", false, "false", "verbose output")
+	tst.RegisterStringFlag("log-level", "", "info", "info", "the log level")
+	tst.SetChoices("log-level", "debug", "info")
+	tst.RegisterIntConfFileVar("workers", 4)
+	tst.AddStringCore("version", "1.0")
+	tst.AddCommand("serve", "run the server", nil)
+	return tst
+}
+
+func TestWriteManPage(t *testing.T) {
+	expected := `.TH "APP" 1
+.SH NAME
+app \- serves things
+.SH SYNOPSIS
+.B app
+[\fIflags\fR]
+\fIcommand\fR [\fIargs\fR]
+.SH DESCRIPTION
+serves things
+.SH COMMANDS
+.TP
+.B serve
+run the server
+.SH OPTIONS
+.TP
+\fB\-\-log\-level\fR \fIstring\fR
+the log level
+.br
+Default: info
+.br
+Choices: debug, info
+.br
+Environment variable: APP_LOG\-LEVEL
+.br
+Configuration file key: log\-level
+.TP
+\fB\-v\fR, \fB\-\-[no\-]verbose\fR
+verbose output
+.br
+Default: false
+.br
+Environment variable: APP_VERBOSE
+.br
+Configuration file key: verbose
+.SH ENVIRONMENT
+.TP
+.B APP_LOG\-LEVEL
+the log level
+.br
+Default: info
+.br
+Choices: debug, info
+.br
+Type: string
+.br
+Flag: \-\-log\-level
+.TP
+.B APP_VERBOSE
+verbose output
+.br
+Default: false
+.br
+Type: bool
+.br
+Flag: \-\-verbose
+.SH CONFIGURATION
+Settings read from the configuration file, \fIapp.json\fR.
+.TP
+.B log\-level
+the log level
+.br
+Default: info
+.br
+Choices: debug, info
+.br
+Type: string
+.TP
+.B verbose
+verbose output
+.br
+Default: false
+.br
+Type: bool
+.TP
+.B workers
+Default: 4
+.br
+Type: int
+`
+	var buf bytes.Buffer
+	err := newDocSettings().WriteManPage(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	expected := "# app\n\nserves things\n\n## Usage\n\n    app [flags] <command> [args]\n" +
+		"\n## Commands\n\n" +
+		"| Command | Description |\n" +
+		"| --- | --- |\n" +
+		"| `serve` | run the server |\n" +
+		"\n## Flags\n\n" +
+		"| Flag | Short | Type | Default | Environment variable | Configuration key | Description |\n" +
+		"| --- | --- | --- | --- | --- | --- | --- |\n" +
+		"| `--log-level` |  | string | `info` | `APP_LOG-LEVEL` | `log-level` | the log level (one of: `debug`, `info`) |\n" +
+		"| `--[no-]verbose` | `-v` | bool | `false` | `APP_VERBOSE` | `verbose` | verbose output |\n" +
+		"\n## Environment variables\n\n" +
+		"| Variable | Type | Default | Description |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| `APP_LOG-LEVEL` | string | `info` | the log level (one of: `debug`, `info`) |\n" +
+		"| `APP_VERBOSE` | bool | `false` | verbose output |\n" +
+		"\n## Configuration file\n\n" +
+		"Settings read from the configuration file, `app.json`.\n\n" +
+		"| Key | Type | Default | Description |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| `log-level` | string | `info` | the log level (one of: `debug`, `info`) |\n" +
+		"| `verbose` | bool | `false` | verbose output |\n" +
+		"| `workers` | int | `4` |  |\n"
+	var buf bytes.Buffer
+	err := newDocSettings().WriteMarkdown(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), expected)
+	}
+}
+
+func TestRoffEscape(t *testing.T) {
+	tests := []struct {
+		v        string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"--port", `\-\-port`},
+		{`C:\path`, `C:\epath`},
+		{".hidden", `\&.hidden`},
+		{"'quoted'", `\&'quoted'`},
+	}
+	for _, test := range tests {
+		if v := roffEscape(test.v); v != test.expected {
+			t.Errorf("%q: got %q; want %q", test.v, v, test.expected)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package contour
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// parseDotEnv parses the contents of a dotenv file and returns the variables
+// it defines. Each variable is defined as KEY=VALUE on its own line. Blank
+// lines and lines starting with a # are ignored and an optional export prefix
+// is allowed. Unquoted values are trimmed and may end with a comment that is
+// preceded by whitespace. Single quoted values are used as is. Double quoted
+// values may contain the escape sequences \n, \r, \t, \", and \\. Quoted
+// values may span multiple lines.
+func parseDotEnv(b []byte) (map[string]string, error) {
+	vars := map[string]string{}
+	src := strings.Replace(string(b), "\r\n", "\n", -1)
+	var line int
+	for len(src) > 0 {
+		var l string
+		l, src = nextLine(src)
+		line++
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		if strings.HasPrefix(l, "export ") || strings.HasPrefix(l, "export\t") {
+			l = strings.TrimSpace(l[len("export"):])
+		}
+		i := strings.IndexByte(l, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: %q: no '=' found", line, l)
+		}
+		k := strings.TrimSpace(l[:i])
+		if !isDotEnvKey(k) {
+			return nil, fmt.Errorf("line %d: %q: invalid variable name", line, k)
+		}
+		v := strings.TrimLeft(l[i+1:], " \t")
+		if v == "" || (v[0] != '"' && v[0] != '\'') {
+			// unquoted: strip any trailing comment
+			if strings.HasPrefix(v, "#") {
+				v = ""
+			}
+			if j := strings.Index(v, " #"); j >= 0 {
+				v = v[:j]
+			}
+			if j := strings.Index(v, "\t#"); j >= 0 {
+				v = v[:j]
+			}
+			vars[k] = strings.TrimSpace(v)
+			continue
+		}
+		// Quoted values may span lines so the closing quote is searched for
+		// in the rest of the file.
+		start := line
+		rest := v[1:] + "\n" + src
+		val, n, ok := unquoteDotEnv(rest, v[0])
+		if !ok {
+			return nil, fmt.Errorf("line %d: %s: unterminated quoted value", start, k)
+		}
+		line += strings.Count(rest[:n], "\n")
+		// anything after the closing quote, on the same line, must be a comment
+		l, src = nextLine(rest[n:])
+		l = strings.TrimSpace(l)
+		if l != "" && !strings.HasPrefix(l, "#") {
+			return nil, fmt.Errorf("line %d: %s: unexpected characters after quoted value: %q", line, k, l)
+		}
+		vars[k] = val
+	}
+	return vars, nil
+}
+
+// nextLine returns the first line in s, without its newline, and the rest of
+// s.
+func nextLine(s string) (line, rest string) {
+	i := strings.IndexByte(s, '\n')
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i+1:]
+}
+
+// unquoteDotEnv returns the value of the quoted string at the start of s, the
+// number of bytes of s that were consumed, including the closing quote, and
+// whether the closing quote, q, was found.
+func unquoteDotEnv(s string, q byte) (string, int, bool) {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == q {
+			return buf.String(), i + 1, true
+		}
+		if c != '\\' || q != '"' || i+1 == len(s) {
+			buf.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 't':
+			buf.WriteByte('\t')
+		case '"', '\\':
+			buf.WriteByte(s[i])
+		default:
+			buf.WriteByte('\\')
+			buf.WriteByte(s[i])
+		}
+	}
+	return "", len(s), false
+}
+
+// isDotEnvKey returns if k is a valid dotenv variable name.
+func isDotEnvKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for i, r := range k {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r == '.', r == '-', r >= '0' && r <= '9':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// loadDotEnv reads the settings' dotenv file, if settings is configured to use
+// one, and caches its variables. This only happens once. A missing dotenv file
+// is not an error. The variables are never added to the process environment.
+// This assumes the caller holds the lock.
+func (s *Settings) loadDotEnv() error {
+	if !s.useDotEnv || s.dotEnvVars != nil {
+		return nil
+	}
+	b, err := s.readDotEnvFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.dotEnvVars = map[string]string{}
+			return nil
+		}
+		return err
+	}
+	vars, err := parseDotEnv(b)
+	if err != nil {
+		return fmt.Errorf("%s: %s", s.dotEnvFilename, err)
+	}
+	s.dotEnvVars = vars
+	return nil
+}
+
+// readDotEnvFile reads the dotenv file. The filename is checked as is, then
+// each of the dotenv paths is checked, in order.
+func (s *Settings) readDotEnvFile() ([]byte, error) {
+	b, err := ioutil.ReadFile(s.dotEnvFilename)
+	if err == nil || !os.IsNotExist(err) {
+		return b, err
+	}
+	if len(s.dotEnvPaths) == 0 {
+		return nil, err
+	}
+	return s.checkPaths(filepath.Base(s.dotEnvFilename), s.dotEnvPaths)
+}
+
+// getenv returns the value of the environment variable k. The process
+// environment takes precedence over the dotenv file. This assumes the caller
+// holds the lock.
+func (s *Settings) getenv(k string) string {
+	v := os.Getenv(k)
+	if v != "" {
+		return v
+	}
+	return s.dotEnvVars[k]
+}
+
+// UseDotEnv returns if settings will read environment variables from a dotenv
+// file, in addition to the process environment.
+func (s *Settings) UseDotEnv() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.useDotEnv
+}
+
+// SetUseDotEnv sets if settings should read environment variables from a
+// dotenv file, in addition to the process environment. Variables in the
+// process environment take precedence over those in the dotenv file.
+func (s *Settings) SetUseDotEnv(b bool) {
+	s.mu.Lock()
+	s.useDotEnv = b
+	s.mu.Unlock()
+}
+
+// DotEnvFilename returns the name of settings' dotenv file.
+func (s *Settings) DotEnvFilename() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dotEnvFilename
+}
+
+// SetDotEnvFilename sets the name of settings' dotenv file and configures
+// settings to use a dotenv file. If the filename is empty, an error is
+// returned.
+func (s *Settings) SetDotEnvFilename(v string) error {
+	if v == "" {
+		return fmt.Errorf("dotenv filename: set failed: no name provided")
+	}
+	s.mu.Lock()
+	s.dotEnvFilename = v
+	s.useDotEnv = true
+	s.mu.Unlock()
+	return nil
+}
+
+// DotEnvPaths returns the paths that settings should check when looking for
+// the dotenv file.
+func (s *Settings) DotEnvPaths() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dotEnvPaths
+}
+
+// SetDotEnvPaths sets the paths that settings should check when looking for
+// the dotenv file. The paths will be checked in the order provided.
+func (s *Settings) SetDotEnvPaths(paths []string) {
+	s.mu.Lock()
+	s.dotEnvPaths = paths
+	s.mu.Unlock()
+}
+
+// UseDotEnv returns if the standard settings will read environment variables
+// from a dotenv file, in addition to the process environment.
+func UseDotEnv() bool { return std.UseDotEnv() }
+
+// SetUseDotEnv sets if the standard settings should read environment
+// variables from a dotenv file, in addition to the process environment.
+func SetUseDotEnv(b bool) { std.SetUseDotEnv(b) }
+
+// DotEnvFilename returns the name of the standard settings' dotenv file.
+func DotEnvFilename() string { return std.DotEnvFilename() }
+
+// SetDotEnvFilename sets the name of the standard settings' dotenv file and
+// configures it to use a dotenv file. If the filename is empty, an error is
+// returned.
+func SetDotEnvFilename(v string) error { return std.SetDotEnvFilename(v) }
+
+// DotEnvPaths returns the paths that the standard settings should check when
+// looking for the dotenv file.
+func DotEnvPaths() []string { return std.DotEnvPaths() }
+
+// SetDotEnvPaths sets the paths that the standard settings should check when
+// looking for the dotenv file. The paths will be checked in the order
+// provided.
+func SetDotEnvPaths(paths []string) { std.SetDotEnvPaths(paths) }
@@ -0,0 +1,111 @@
+package contour
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected map[string]string
+		err      string
+	}{
+		{"empty", "", map[string]string{}, ""},
+		{"comments", "# a comment\n\n   # another\n", map[string]string{}, ""},
+		{"simple", "FOO=bar\nBAZ = biz \n", map[string]string{"FOO": "bar", "BAZ": "biz"}, ""},
+		{"export", "export FOO=bar\nexport\tBAR=baz", map[string]string{"FOO": "bar", "BAR": "baz"}, ""},
+		{"empty value", "FOO=\nBAR= # nothing", map[string]string{"FOO": "", "BAR": ""}, ""},
+		{"inline comment", "FOO=bar # comment\nBAR=b#z", map[string]string{"FOO": "bar", "BAR": "b#z"}, ""},
+		{"single quoted", "FOO='bar # \\n baz'", map[string]string{"FOO": "bar # \\n baz"}, ""},
+		{"double quoted", `FOO="bar\n\t\"baz\"\\"`, map[string]string{"FOO": "bar\n\t\"baz\"\\"}, ""},
+		{"multiline", "FOO=\"line 1\nline 2\"\nBAR='a\nb' # comment\nBAZ=z", map[string]string{"FOO": "line 1\nline 2", "BAR": "a\nb", "BAZ": "z"}, ""},
+		{"crlf", "FOO=bar\r\nBAR=\"a\r\nb\"\r\n", map[string]string{"FOO": "bar", "BAR": "a\nb"}, ""},
+		{"no equals", "FOO=bar\nBAR", nil, "line 2: \"BAR\": no '=' found"},
+		{"bad name", "1FOO=bar", nil, "line 1: \"1FOO\": invalid variable name"},
+		{"unterminated", "FOO=bar\nBAR=\"baz\nBIZ=x", nil, "line 2: BAR: unterminated quoted value"},
+		{"trailing", "FOO=\"a\nb\" c", nil, "line 2: FOO: unexpected characters after quoted value: \"c\""},
+	}
+	for _, test := range tests {
+		vars, err := parseDotEnv([]byte(test.data))
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%s: got %q; want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%s: got no error; want %q", test.name, test.err)
+			continue
+		}
+		if !reflect.DeepEqual(vars, test.expected) {
+			t.Errorf("%s: got %#v; want %#v", test.name, vars, test.expected)
+		}
+	}
+}
+
+func TestDotEnv(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "test.env"), []byte("DOTENV_STRING=from dotenv\nexport DOTENV_INT=7\nDOTENV_BOOL=true\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tst := New("dotenv")
+	tst.RegisterStringEnvVar("string", "default")
+	tst.RegisterIntEnvVar("int", 1)
+	tst.RegisterBoolEnvVar("bool", false)
+	tst.useConfFile = false
+	if tst.UseDotEnv() {
+		t.Error("UseDotEnv: got true; want false")
+	}
+	err = tst.SetDotEnvFilename("")
+	if err == nil {
+		t.Error("SetDotEnvFilename: expected an error, got none")
+	}
+	err = tst.SetDotEnvFilename("test.env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tst.SetDotEnvPaths([]string{filepath.Join(tmpDir, "nothere"), tmpDir})
+	os.Setenv("DOTENV_INT", "11")
+	defer os.Unsetenv("DOTENV_INT")
+	err = tst.Set()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v := tst.String("string"); v != "from dotenv" {
+		t.Errorf("string: got %q; want %q", v, "from dotenv")
+	}
+	// the process environment takes precedence
+	if v := tst.Int("int"); v != 11 {
+		t.Errorf("int: got %d; want 11", v)
+	}
+	if v := tst.Bool("bool"); !v {
+		t.Errorf("bool: got %v; want true", v)
+	}
+	// the dotenv vars must not end up in the environment
+	if v := os.Getenv("DOTENV_STRING"); v != "" {
+		t.Errorf("DOTENV_STRING env var: got %q; want \"\"", v)
+	}
+	// a missing dotenv file is not an error
+	tst = New("dotenv")
+	tst.RegisterStringEnvVar("string", "default")
+	tst.useConfFile = false
+	tst.SetUseDotEnv(true)
+	tst.SetDotEnvFilename(filepath.Join(tmpDir, "missing.env"))
+	err = tst.Set()
+	if err != nil {
+		t.Fatalf("missing dotenv file: unexpected error: %s", err)
+	}
+	if v := tst.String("string"); v != "default" {
+		t.Errorf("string: got %q; want %q", v, "default")
+	}
+}
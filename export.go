@@ -0,0 +1,123 @@
+package contour
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// ExportConf writes the current values of settings' configuration file
+// settings to w as a configuration in the format f. The configuration can be
+// read back, e.g. as settings' configuration file, with the same values: the
+// values of INI and .properties configurations are written like they are
+// parsed, an interface{} setting's value is written as JSON and a flag.Value's
+// is its String. JSON5 and HCL configurations are written as JSON, which both
+// of them are a superset of. The settings whose value is nil aren't written.
+//
+// If f isn't a supported format, an UnsupportedFormatError is returned.
+func (s *Settings) ExportConf(w io.Writer, f Format) error {
+	if !f.isSupported() {
+		return UnsupportedFormatError{f.String()}
+	}
+	s.mu.RLock()
+	m, err := s.confExportValues()
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	b, err := marshalConfBytes(f, m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// confExportValues returns the current values of settings' configuration file
+// settings, by name. This assumes the lock has been obtained.
+func (s *Settings) confExportValues() (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	for k, v := range s.settings {
+		if !v.IsConfFileVar {
+			continue
+		}
+		val, err := s.get(k)
+		if err != nil {
+			return nil, err
+		}
+		if fv, ok := val.(flag.Value); ok {
+			val = fv.String()
+		}
+		// a parsed flag's value is a pointer to it
+		if rv := reflect.ValueOf(val); rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				continue
+			}
+			val = rv.Elem().Interface()
+		}
+		if val == nil {
+			continue
+		}
+		m[k] = val
+	}
+	return m, nil
+}
+
+// marshalConfBytes returns m as a configuration in the format f. The values
+// of formats without types are strings: a string is itself, an interface{}
+// value is JSON, and anything else is formatted with its default format.
+func marshalConfBytes(f Format, m map[string]interface{}) ([]byte, error) {
+	switch f {
+	// JSON is JSON5 and HCL, too
+	case JSON, JSON5, HCL:
+		b, err := json.MarshalIndent(m, "", "\t")
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	case TOML:
+		var buf bytes.Buffer
+		err := toml.NewEncoder(&buf).Encode(m)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case YAML:
+		return yaml.Marshal(m)
+	}
+	if f.isTyped() {
+		return nil, UnsupportedFormatError{f.String()}
+	}
+	sm := make(map[string]string, len(m))
+	for k, v := range m {
+		switch x := v.(type) {
+		case string:
+			sm[k] = x
+		case bool, int, int64:
+			sm[k] = fmt.Sprint(x)
+		default:
+			b, err := json.Marshal(x)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", k, err)
+			}
+			sm[k] = string(b)
+		}
+	}
+	switch f {
+	case INI:
+		return marshalINI(sm)
+	case XML:
+		return marshalXML(sm)
+	}
+	return marshalProperties(sm)
+}
+
+// ExportConf writes the current values of the standard settings'
+// configuration file settings to w in the format f. See Settings.ExportConf.
+func ExportConf(w io.Writer, f Format) error { return std.ExportConf(w, f) }
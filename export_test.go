@@ -0,0 +1,57 @@
+package contour
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestExportConf(t *testing.T) {
+	register := func(s *Settings) {
+		s.RegisterStringConfFileVar("name", "")
+		s.RegisterBoolConfFileVar("debug", false)
+		s.RegisterStringConfFileVar("db.host", "")
+		s.RegisterIntConfFileVar("db.port", 0)
+		s.RegisterInt64ConfFileVar("db.max_conns", 0)
+		s.RegisterInterfaceConfFileVar("labels", nil)
+		s.RegisterStringEnvVar("token", "")
+	}
+	src := New("app")
+	register(src)
+	src.UpdateString("name", "app")
+	src.UpdateBool("debug", true)
+	src.UpdateString("db.host", "db.example.com")
+	src.UpdateInt("db.port", 5432)
+	src.UpdateInt64("db.max_conns", 9000000000)
+	src.UpdateInterface("labels", map[string]interface{}{"env": "prod", "tier": "1"})
+	src.UpdateString("token", "secret")
+
+	for _, f := range []Format{JSON, JSON5, TOML, YAML, HCL, INI, Properties, XML} {
+		var buf bytes.Buffer
+		err := src.ExportConf(&buf, f)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", f, err)
+			continue
+		}
+		if bytes.Contains(buf.Bytes(), []byte("secret")) {
+			t.Errorf("%s: got the env var in the export:\n%s", f, buf.String())
+		}
+		dst := New("app")
+		register(dst)
+		err = dst.SetFromReader(&buf, f)
+		if err != nil {
+			t.Errorf("%s: unexpected error reading the export: %s", f, err)
+			continue
+		}
+		for _, k := range []string{"name", "debug", "db.host", "db.port", "db.max_conns", "labels"} {
+			if got, want := dst.Get(k), src.Get(k); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: %s: got %#v; want %#v", f, k, got, want)
+			}
+		}
+	}
+
+	err := src.ExportConf(&bytes.Buffer{}, Unsupported)
+	if err == nil || err.Error() != "unsupported: unsupported configuration format" {
+		t.Errorf("unsupported: got %v; want an UnsupportedFormatError", err)
+	}
+}
@@ -1,11 +1,14 @@
 package contour
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
+	"strings"
 )
 
 var (
@@ -18,6 +21,17 @@ var (
 // non-flag args are returned to the caller and a list of flags in the args is
 // cached.
 //
+// If settings is set to use POSIX flags, see SetPOSIXFlags, the args are parsed
+// using POSIX/GNU conventions. If settings is set to use response files, see
+// SetUseResponseFiles, any @path args are expanded before the args are parsed.
+//
+// Once the flags have been parsed, settings' args are set from the non-flag
+// args, see RegisterArg, and settings' groups are checked, see
+// MutuallyExclusive and RequiredTogether.
+//
+// If settings has the completion flag, see SetCompletionFlag, and it was used,
+// the completion script is written and an ErrCompletion is returned.
+//
 // If settings is not set to use flags, the args will be returned along with an
 // ErrUseFlagsFalse. If settings has already parsed flags, the args are
 // returned along with an ErrFlagsParsed.
@@ -39,45 +53,194 @@ func (s *Settings) parseFlags(args []string) ([]string, error) {
 	if s.flagsParsed {
 		return args, ErrFlagsParsed
 	}
+	// the default configuration's values are the flags' defaults
+	err := s.setDefaultConf()
+	if err != nil {
+		return nil, err
+	}
 	// Get the flag information and set the flagSet
 	s.setFlags()
+	s.setCompletionFlag()
+	s.setOverrideFlag()
+	s.setProfileFlag()
+	s.setInheritedFlags()
+
+	if s.useResponseFiles {
+		var sources []string
+		args, sources, err = expandResponseFiles(args, "", "", 0)
+		if err != nil {
+			return nil, err
+		}
+		s.responseFileFlags = s.responseFileSources(args, sources)
+	}
 
 	// Parse args for flags
-	err := s.flagSet.Parse(args)
+	var cmdArgs []string
+	if s.posixFlags {
+		cmdArgs, err = s.parsePOSIXFlags(args)
+	} else {
+		err = s.flagSet.Parse(args)
+		cmdArgs = s.flagSet.Args()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("parse of command-line arguments failed: %s", err)
 	}
+	err = s.completion()
+	if err != nil {
+		return nil, err
+	}
+
+	// Update settings with the values of the flags that were used
+	vals, err := flagSource{s: s}.Load()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s.setFlag(k, vals[k])
+	}
+	// the flags that aren't settings' own may be inherited flags
+	s.flagSet.Visit(func(f *flag.Flag) {
+		if _, _, ok := s.flagValue(f); !ok {
+			s.visitedInherited(f)
+		}
+	})
 
-	// get the visited flags
-	var visited []*flag.Flag
-	visitor := func(a *flag.Flag) {
-		visited = append(visited, a)
+	err = s.forwardDeprecatedFlags()
+	if err != nil {
+		return nil, err
+	}
+	err = s.applyOverrides()
+	if err != nil {
+		return nil, err
 	}
+	err = s.applyProfileFlag()
+	if err != nil {
+		return nil, err
+	}
+	// sort the parsed flagsParsed
+	sort.Strings(s.parsedFlags)
 
-	s.flagSet.Visit(visitor)
-	// Update settings with the updated flag values
-	for _, f := range visited {
-		v, ok := s.settings[f.Name]
+	s.flagsParsed = true
+	err = s.setArgs(cmdArgs)
+	if err != nil {
+		return cmdArgs, err
+	}
+	// the settings are final
+	return cmdArgs, s.checkGroups()
+}
+
+// visited updates the setting for the visited flag f with the flag's value
+// and records it as a parsed flag. If f isn't one of the settings' flags,
+// false is returned. This assumes the lock has been obtained.
+func (s *Settings) visited(f *flag.Flag) bool {
+	k, v, ok := s.flagValue(f)
+	if !ok {
+		return false
+	}
+	for _, n := range s.parsedFlags {
+		if n == k && !agrees(f, v.Value) {
+			v.Origin = s.settings[k].Origin
+			break
+		}
+	}
+	s.setFlag(k, v)
+	return true
+}
+
+// flagValue returns the name of the setting whose flag is f and the flag's
+// value. If f isn't one of the settings' flags, false is returned. This
+// assumes the lock has been obtained.
+func (s *Settings) flagValue(f *flag.Flag) (string, SourceValue, bool) {
+	name, value := f.Name, f.Value
+	// a negated flag sets its bool flag
+	if nv, ok := f.Value.(*negBoolValue); ok {
+		name, value = strings.TrimPrefix(f.Name, negFlagPrefix), (*boolValue)(nv)
+	}
+	v, ok := s.settings[name]
+	if !ok {
+		// see if it's a short flag or an alias
+		v, ok = s.settings[s.shortFlags[name]]
 		if !ok {
-			// see if it's a short flag
-			v, ok = s.settings[s.shortFlags[f.Name]]
+			v, ok = s.settings[s.aliases[name]]
 			if !ok {
-				continue
+				return "", SourceValue{}, false
 			}
 		}
-		v.Value = f.Value
-		s.settings[v.Name] = v
-		s.parsedFlags = append(s.parsedFlags, v.Name)
 	}
+	origin := "flag " + s.flagPrefix(f.Name) + f.Name
+	if src, ok := s.responseFileFlags[f.Name]; ok {
+		origin = "response file " + src + " (" + origin + ")"
+	}
+	return v.Name, SourceValue{Value: value, Origin: origin}, true
+}
 
-	// sort the parsed flagsParsed
+// agrees returns whether the flag f agrees with value, its setting's value.
+// If both a bool flag and its negated flag were used, the origin is the one
+// that agrees with the value; otherwise, it's the first flag that was used.
+func agrees(f *flag.Flag, value interface{}) bool {
+	b, ok := value.(*boolValue)
+	if !ok {
+		return false
+	}
+	_, neg := f.Value.(*negBoolValue)
+	return bool(*b) != neg
+}
+
+// setFlag updates setting k with v, its flag's value, and records it as a
+// parsed flag, unless a source with higher precedence than flags set it. This
+// assumes the lock has been obtained.
+func (s *Settings) setFlag(k string, v SourceValue) {
+	val := s.settings[k]
+	if s.overridden(val, Flag) {
+		return
+	}
+	val.Value, val.Origin, val.SetBy = v.Value, v.Origin, Flag
+	s.settings[k] = val
+	for _, n := range s.parsedFlags {
+		if n == k {
+			return
+		}
+	}
+	s.parsedFlags = append(s.parsedFlags, k)
 	sort.Strings(s.parsedFlags)
+}
 
-	// Get the remaining args
-	cmdArgs := s.flagSet.Args()
+// flagPrefix returns the dashes that precede the flag name on the command
+// line. This assumes the lock has been obtained.
+func (s *Settings) flagPrefix(name string) string {
+	if !s.posixFlags {
+		return "-"
+	}
+	if _, ok := s.shortFlags[name]; ok {
+		if _, ok := s.settings[name]; !ok {
+			return "-"
+		}
+	}
+	return "--"
+}
 
-	s.flagsParsed = true
-	return cmdArgs, nil
+// longFlag returns the flag's name as used on the command line. This assumes
+// the lock has been obtained.
+func (s *Settings) longFlag(name string) string {
+	if s.posixFlags {
+		return "--" + name
+	}
+	return "-" + name
+}
+
+// flagName returns setting v's flag's name as shown in usage and
+// documentation. If the flag is negatable, it's shown as --[no-]name. This
+// assumes the lock has been obtained.
+func (s *Settings) flagName(v setting) string {
+	if s.negatable(v) {
+		return s.longFlag("[" + negFlagPrefix + "]" + v.Name)
+	}
+	return s.longFlag(v.Name)
 }
 
 // setFlags goes through all the settings and sets the flagset vars for any
@@ -90,30 +253,179 @@ func (s *Settings) setFlags() {
 		if v.IsFlag {
 			switch v.Type {
 			case _bool:
-				s.flagVars[v.Name] = s.flagSet.Bool(v.Name, v.Value.(bool), v.Usage)
-				if v.Short != "" {
-					s.flagSet.BoolVar(s.flagVars[v.Name].(*bool), v.Short, v.Value.(bool), v.Usage)
+				p := new(bool)
+				*p = v.Value.(bool)
+				s.flagVars[v.Name] = p
+				s.flagSet.Var((*boolValue)(p), v.Name, v.Usage)
+				for _, n := range flagAliases(v) {
+					s.flagSet.Var((*boolValue)(p), n, v.Usage)
+				}
+				if s.negatable(v) {
+					s.flagSet.Var((*negBoolValue)(p), negFlagPrefix+v.Name, v.Usage)
 				}
 			case _int:
 				s.flagVars[v.Name] = s.flagSet.Int(v.Name, v.Value.(int), v.Usage)
-				if v.Short != "" {
-					s.flagSet.IntVar(s.flagVars[v.Name].(*int), v.Short, v.Value.(int), v.Usage)
+				for _, n := range flagAliases(v) {
+					s.flagSet.IntVar(s.flagVars[v.Name].(*int), n, v.Value.(int), v.Usage)
 				}
 			case _int64:
 				s.flagVars[v.Name] = s.flagSet.Int64(v.Name, v.Value.(int64), v.Usage)
-				if v.Short != "" {
-					s.flagSet.Int64Var(s.flagVars[v.Name].(*int64), v.Short, v.Value.(int64), v.Usage)
+				for _, n := range flagAliases(v) {
+					s.flagSet.Int64Var(s.flagVars[v.Name].(*int64), n, v.Value.(int64), v.Usage)
 				}
 			case _string:
 				s.flagVars[v.Name] = s.flagSet.String(v.Name, v.Value.(string), v.Usage)
-				if v.Short != "" {
-					s.flagSet.StringVar(s.flagVars[v.Name].(*string), v.Short, v.Value.(string), v.Usage)
+				for _, n := range flagAliases(v) {
+					s.flagSet.StringVar(s.flagVars[v.Name].(*string), n, v.Value.(string), v.Usage)
+				}
+			case _interface:
+				// a value that is a flag.Value sets itself; anything else
+				// is set from JSON.
+				fv, ok := v.Value.(flag.Value)
+				if !ok {
+					fv = &jsonValue{v: v.Value}
+				}
+				s.flagVars[v.Name] = fv
+				s.flagSet.Var(fv, v.Name, v.Usage)
+				for _, n := range flagAliases(v) {
+					s.flagSet.Var(fv, n, v.Usage)
 				}
 			}
 		}
 	}
 }
 
+// boolValue is a flag.Value for bool flags. Unlike the flag package's bool
+// flags, the value is parsed with parseBool so that the values accepted are
+// consistent with those accepted from environment variables and configuration
+// files.
+type boolValue bool
+
+func (b *boolValue) Set(s string) error {
+	v, err := parseBool(s)
+	if err != nil {
+		return err
+	}
+	*b = boolValue(v)
+	return nil
+}
+
+func (b *boolValue) Get() interface{} { return bool(*b) }
+
+func (b *boolValue) String() string {
+	if b == nil {
+		return "false"
+	}
+	return strconv.FormatBool(bool(*b))
+}
+
+func (b *boolValue) IsBoolFlag() bool { return true }
+
+// The prefix of a bool flag's negated flag, e.g. --no-verbose.
+const negFlagPrefix = "no-"
+
+// negatable returns if setting v's flag has a negated flag, --no-name, that
+// sets it to false. Bool flags are negatable unless there's a setting, short
+// flag, or alias with the negated flag's name. This assumes the lock has been
+// obtained.
+func (s *Settings) negatable(v setting) bool {
+	if !v.IsFlag || v.Type != _bool {
+		return false
+	}
+	if _, ok := s.settings[negFlagPrefix+v.Name]; ok {
+		return false
+	}
+	if _, ok := s.aliases[negFlagPrefix+v.Name]; ok {
+		return false
+	}
+	_, ok := s.shortFlags[negFlagPrefix+v.Name]
+	return !ok
+}
+
+// negBoolValue is the flag.Value for a bool flag's negated flag. It shares
+// the bool flag's value; setting it to true sets the bool flag to false.
+type negBoolValue bool
+
+func (b *negBoolValue) Set(s string) error {
+	v, err := parseBool(s)
+	if err != nil {
+		return err
+	}
+	*b = negBoolValue(!v)
+	return nil
+}
+
+func (b *negBoolValue) Get() interface{} { return !bool(*b) }
+
+func (b *negBoolValue) String() string {
+	if b == nil {
+		return "false"
+	}
+	return strconv.FormatBool(!bool(*b))
+}
+
+func (b *negBoolValue) IsBoolFlag() bool { return true }
+
+// jsonValue is the flag.Value for interface{} flags whose value isn't a
+// flag.Value. The flag's value is parsed in the same way as an interface{}
+// environment variable: as JSON or, if it isn't valid JSON, as YAML.
+type jsonValue struct {
+	v interface{}
+}
+
+func (j *jsonValue) Set(s string) error {
+	v, err := parseInterface(s)
+	if err != nil {
+		return err
+	}
+	j.v = v
+	return nil
+}
+
+func (j *jsonValue) Get() interface{} { return j.v }
+
+func (j *jsonValue) String() string {
+	if j == nil {
+		return ""
+	}
+	b, err := json.Marshal(j.v)
+	if err != nil {
+		return fmt.Sprintf("%v", j.v)
+	}
+	return string(b)
+}
+
+// checkFlagValue returns an error if value cannot be the value of a flag of
+// data type typ: bool, int, int64, and string flags must have a value of that
+// type; an interface{} flag's value must either be a flag.Value or be
+// encodable as JSON.
+func checkFlagValue(typ dataType, name string, value interface{}) error {
+	var ok bool
+	switch typ {
+	case _bool:
+		_, ok = value.(bool)
+	case _int:
+		_, ok = value.(int)
+	case _int64:
+		_, ok = value.(int64)
+	case _string:
+		_, ok = value.(string)
+	case _interface:
+		if _, ok := value.(flag.Value); ok {
+			return nil
+		}
+		_, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("%s: unsupported flag value: %T is neither a flag.Value nor JSON encodable: %s", name, value, err)
+		}
+		return nil
+	}
+	if !ok {
+		return DataTypeError{k: name, is: fmt.Sprintf("%T", value), not: typ}
+	}
+	return nil
+}
+
 // Visited returns the names of all settings' flags that were set during flag
 // parsing, in lexical order.
 func (s *Settings) Visited() []string { return s.parsedFlags }
@@ -2,6 +2,7 @@ package contour
 
 import (
 	"fmt"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
@@ -228,3 +229,216 @@ TESTS:
 		}
 	}
 }
+
+func TestParseBoolFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected bool
+		err      string
+	}{
+		{[]string{"-flagbool-tst=false"}, false, ""},
+		{[]string{"-flagbool-tst=no"}, false, ""},
+		{[]string{"-b=off"}, false, ""},
+		{[]string{"-flagbool-tst=yes"}, true, ""},
+		{[]string{"-flagbool-tst"}, true, ""},
+		{[]string{"-flagbool-tst=maybe"}, false, `parse of command-line arguments failed: invalid boolean value "maybe" for -flagbool-tst: ` + errInvalidBool.Error()},
+	}
+	for _, test := range tests {
+		tst := newTestSettings()
+		tst.useFlags = true
+		tst.SetUsage(func() {})
+		_, err := tst.ParseFlags(test.args)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%v: got %q; want %q", test.args, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%v: got no error; want %q", test.args, test.err)
+			continue
+		}
+		k := "flagbool-tst"
+		if test.args[0][1] == 'b' {
+			k = "flagbool"
+		}
+		if v := tst.Bool(k); v != test.expected {
+			t.Errorf("%v: got %v; want %v", test.args, v, test.expected)
+		}
+	}
+}
+
+func TestNegatedBoolFlags(t *testing.T) {
+	tests := []struct {
+		posix    bool
+		args     []string
+		expected bool
+		origin   string
+		err      string
+	}{
+		{false, nil, true, "default", ""},
+		{false, []string{"-no-color"}, false, "flag -no-color", ""},
+		{false, []string{"-no-color=false"}, true, "flag -no-color", ""},
+		{false, []string{"-color=false"}, false, "flag -color", ""},
+		{false, []string{"-no-color", "-color"}, true, "flag -color", ""},
+		{true, []string{"--no-color"}, false, "flag --no-color", ""},
+		{true, []string{"-c", "--no-color"}, false, "flag --no-color", ""},
+		{false, []string{"-no-color=yes", "-c"}, true, "flag -c", ""},
+		{false, []string{"-no-color=yes"}, false, "flag -no-color", ""},
+		{false, []string{"-no-color=maybe"}, false, "", `parse of command-line arguments failed: invalid boolean value "maybe" for -no-color: ` + errInvalidBool.Error()},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetPOSIXFlags(test.posix)
+		tst.SetUsage(func() {})
+		tst.RegisterBoolFlag("color", "c", true, "true", "colorize the output")
+		tst.RegisterBoolFlag("cache", "", true, "true", "cache results")
+		// a setting whose name is a bool flag's negated flag
+		tst.RegisterBoolFlag("no-cache", "", false, "false", "don't cache results")
+		_, err := tst.ParseFlags(test.args)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%v: got %q; want %q", test.args, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%v: got no error; want %q", test.args, test.err)
+			continue
+		}
+		if v := tst.Bool("color"); v != test.expected {
+			t.Errorf("%v: got %v; want %v", test.args, v, test.expected)
+		}
+		if o := tst.Origin("color"); o != test.origin {
+			t.Errorf("%v: origin: got %q; want %q", test.args, o, test.origin)
+		}
+		if len(test.args) > 0 && !reflect.DeepEqual(tst.Visited(), []string{"color"}) {
+			t.Errorf("%v: visited: got %v; want [color]", test.args, tst.Visited())
+		}
+	}
+
+	// no-cache is its own flag
+	tst := New("app")
+	tst.SetUsage(func() {})
+	tst.RegisterBoolFlag("cache", "", true, "true", "cache results")
+	tst.RegisterBoolFlag("no-cache", "", false, "false", "don't cache results")
+	_, err := tst.ParseFlags([]string{"-no-cache"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !tst.Bool("cache") || !tst.Bool("no-cache") {
+		t.Errorf("got cache %v, no-cache %v; want true, true", tst.Bool("cache"), tst.Bool("no-cache"))
+	}
+}
+
+// listValue is a flag.Value that appends each value it's set to.
+type listValue []string
+
+func (l *listValue) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func TestInterfaceFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected interface{}
+		err      string
+	}{
+		{nil, map[string]interface{}{"a": 1}, ""},
+		{[]string{"-labels", `{"env": "prod", "n": 2}`}, map[string]interface{}{"env": "prod", "n": float64(2)}, ""},
+		{[]string{"-l", `["a", "b"]`}, []interface{}{"a", "b"}, ""},
+		{[]string{"-labels", `{"env": `}, nil, `parse of command-line arguments failed: invalid value "{\"env\": " for flag -labels: unexpected end of JSON input`},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetErrOnMissingConfFile(false)
+		tst.SetUsage(func() {})
+		err := tst.RegisterSetting("interface{}", "labels", "l", map[string]interface{}{"a": 1}, `{"a":1}`, "labels", false, false, false, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		_, err = tst.ParseFlags(test.args)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%v: got %q; want %q", test.args, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%v: got no error; want %q", test.args, test.err)
+			continue
+		}
+		if v := tst.Interface("labels"); !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("%v: got %#v; want %#v", test.args, v, test.expected)
+		}
+	}
+}
+
+func TestFlagValueFlags(t *testing.T) {
+	os.Setenv("APP_TAGS", "env")
+	defer os.Unsetenv("APP_TAGS")
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.SetUsage(func() {})
+	tags := &listValue{}
+	err := tst.RegisterSetting("interface{}", "tags", "t", tags, "", "tags", false, false, true, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = tst.Set()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, err = tst.ParseFlags([]string{"-tags", "a", "-t", "b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(*tags, listValue{"env", "a", "b"}) {
+		t.Errorf("got %v; want [env a b]", *tags)
+	}
+	if v := tst.Interface("tags"); v != tags {
+		t.Errorf("got %#v; want %#v", v, tags)
+	}
+	if o := tst.Origin("tags"); o != "flag -t" {
+		t.Errorf("origin: got %q; want %q", o, "flag -t")
+	}
+}
+
+func TestRegisterFlagValues(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value interface{}
+		err   string
+	}{
+		{"bool", true, ""},
+		{"int", "1", "x is string, not int"},
+		{"int64", 1, "x is int, not int64"},
+		{"string", nil, "x is <nil>, not string"},
+		{"interface{}", nil, ""},
+		{"interface{}", []int{1, 2}, ""},
+		{"interface{}", &listValue{}, ""},
+		{"interface{}", func() {}, "x: unsupported flag value: func() is neither a flag.Value nor JSON encodable: json: unsupported type: func()"},
+		{"interface{}", make(chan int), "x: unsupported flag value: chan int is neither a flag.Value nor JSON encodable: json: unsupported type: chan int"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		err := tst.RegisterSetting(test.typ, "x", "", test.value, "", "", false, false, false, true)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%s %T: got %q; want %q", test.typ, test.value, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%s %T: got no error; want %q", test.typ, test.value, test.err)
+		}
+	}
+}
@@ -21,12 +21,20 @@ func (s *Settings) GetE(k string) (interface{}, error) {
 func (s *Settings) get(k string) (interface{}, error) {
 	v, ok := s.settings[k]
 	if !ok {
+		// a command's settings inherit its parent's
+		if s.parent != nil {
+			return s.parent.GetE(k)
+		}
 		return nil, SettingNotFoundError{k: k}
 	}
-	// if this is a flag see if it was parsed; if so return the parsed value
-	if v.IsFlag {
+	// if this is a flag see if it was parsed; if so return the parsed value,
+	// unless a source with higher precedence has since set it.
+	if v.IsFlag && v.SetBy == Flag {
 		for _, x := range s.parsedFlags {
 			if x == v.Name {
+				if jv, ok := s.flagVars[k].(*jsonValue); ok {
+					return jv.v, nil
+				}
 				return s.flagVars[k], nil
 			}
 		}
@@ -0,0 +1,142 @@
+package contour
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrGroupTooSmall occurs when a group of settings has fewer than two
+// settings.
+var ErrGroupTooSmall = errors.New("a group must have at least two settings")
+
+// groupType is the kind of constraint that a group of settings has.
+type groupType int
+
+const (
+	mutuallyExclusive groupType = iota + 1
+	requiredTogether
+)
+
+// group is a constraint on a group of settings.
+type group struct {
+	typ  groupType
+	keys []string
+}
+
+// MutuallyExclusiveError occurs when more than one setting of a mutually
+// exclusive group was set. The origin of each setting's value is included.
+type MutuallyExclusiveError struct {
+	keys    []string // the settings that were set
+	origins []string
+}
+
+func (e MutuallyExclusiveError) Error() string {
+	return fmt.Sprintf("%s: mutually exclusive: %s", strings.Join(e.keys, ", "), setBy(e.keys, e.origins))
+}
+
+// RequiredTogetherError occurs when some, but not all, of the settings in a
+// group that are required together were set. The origin of each set
+// setting's value is included.
+type RequiredTogetherError struct {
+	keys    []string // the settings that were set
+	origins []string
+	missing []string // the settings that weren't set
+}
+
+func (e RequiredTogetherError) Error() string {
+	return fmt.Sprintf("%s: required together: %s; %s not set", strings.Join(append(append([]string{}, e.keys...), e.missing...), ", "), setBy(e.keys, e.origins), strings.Join(e.missing, ", "))
+}
+
+// setBy returns which setting was set by what, e.g.
+// "json set by flag --json; yaml set by env var APP_YAML".
+func setBy(keys, origins []string) string {
+	by := make([]string, len(keys))
+	for i := range keys {
+		by[i] = keys[i] + " set by " + origins[i]
+	}
+	return strings.Join(by, "; ")
+}
+
+// MutuallyExclusive adds a group of settings, keys, that are mutually
+// exclusive: at most one of them may be set, whether by a configuration file,
+// an environment variable, a flag, or an update. A setting that has its
+// default value, or the default configuration's, see SetDefaultConf, is not
+// set. The group is checked once settings' values are final: after the flags
+// have been parsed or, if settings doesn't use flags, after Set. If more than
+// one was set, a MutuallyExclusiveError is returned.
+//
+// If any of the keys don't exist in settings, a SettingNotFoundError is
+// returned. If there are fewer than two keys, an ErrGroupTooSmall is
+// returned.
+func (s *Settings) MutuallyExclusive(keys ...string) error {
+	return s.addGroup(mutuallyExclusive, keys)
+}
+
+// RequiredTogether adds a group of settings, keys, that are required
+// together: if any of them is set, all of them must be set, e.g. a TLS
+// certificate and key. Like MutuallyExclusive, a setting that has its default
+// value, or the default configuration's, is not set. The group is checked
+// once settings' values are final: after the flags have been parsed or, if
+// settings doesn't use flags, after Set. If only some of them were set, a
+// RequiredTogetherError is returned.
+//
+// If any of the keys don't exist in settings, a SettingNotFoundError is
+// returned. If there are fewer than two keys, an ErrGroupTooSmall is
+// returned.
+func (s *Settings) RequiredTogether(keys ...string) error {
+	return s.addGroup(requiredTogether, keys)
+}
+
+func (s *Settings) addGroup(typ groupType, keys []string) error {
+	if len(keys) < 2 {
+		return ErrGroupTooSmall
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range keys {
+		if _, ok := s.settings[k]; !ok {
+			return SettingNotFoundError{k: k}
+		}
+	}
+	s.groups = append(s.groups, group{typ: typ, keys: append([]string(nil), keys...)})
+	return nil
+}
+
+// checkGroups checks that settings' values satisfy its groups' constraints. A
+// setting that wasn't set by a source, e.g. its value is the default or the
+// default configuration's, isn't set. The first group that isn't satisfied
+// results in an error. This assumes the lock has been obtained.
+func (s *Settings) checkGroups() error {
+	for _, g := range s.groups {
+		var set, origins, missing []string
+		for _, k := range g.keys {
+			v := s.settings[k]
+			if v.SetBy == 0 {
+				missing = append(missing, k)
+				continue
+			}
+			set = append(set, k)
+			origins = append(origins, v.Origin)
+		}
+		switch g.typ {
+		case mutuallyExclusive:
+			if len(set) > 1 {
+				return MutuallyExclusiveError{keys: set, origins: origins}
+			}
+		case requiredTogether:
+			if len(set) > 0 && len(missing) > 0 {
+				return RequiredTogetherError{keys: set, origins: origins, missing: missing}
+			}
+		}
+	}
+	return nil
+}
+
+// MutuallyExclusive adds a group of the standard settings' settings that are
+// mutually exclusive. See Settings.MutuallyExclusive.
+func MutuallyExclusive(keys ...string) error { return std.MutuallyExclusive(keys...) }
+
+// RequiredTogether adds a group of the standard settings' settings that are
+// required together. See Settings.RequiredTogether.
+func RequiredTogether(keys ...string) error { return std.RequiredTogether(keys...) }
@@ -0,0 +1,134 @@
+package contour
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGroups(t *testing.T) {
+	tests := []struct {
+		args []string
+		env  map[string]string
+		err  string
+	}{
+		{nil, nil, ""},
+		{[]string{"-json"}, nil, ""},
+		{[]string{"-json", "-yaml"}, nil, "json, yaml: mutually exclusive: json set by flag -json; yaml set by flag -yaml"},
+		{[]string{"-json"}, map[string]string{"APP_YAML": "true"}, "json, yaml: mutually exclusive: json set by flag -json; yaml set by env var APP_YAML"},
+		{[]string{"-tls-cert", "cert.pem", "-tls-key", "key.pem"}, nil, ""},
+		{[]string{"-tls-cert", "cert.pem"}, nil, "tls-cert, tls-key: required together: tls-cert set by flag -tls-cert; tls-key not set"},
+		{nil, map[string]string{"APP_TLS-KEY": "key.pem"}, "tls-key, tls-cert: required together: tls-key set by env var APP_TLS-KEY; tls-cert not set"},
+		{[]string{"-tls-cert", "cert.pem"}, map[string]string{"APP_TLS-KEY": "key.pem"}, ""},
+	}
+	for _, test := range tests {
+		for k, v := range test.env {
+			os.Setenv(k, v)
+		}
+		tst := New("app")
+		tst.SetErrOnMissingConfFile(false)
+		tst.SetUsage(func() {})
+		tst.RegisterBoolFlag("json", "", false, "false", "")
+		tst.RegisterBoolFlag("yaml", "", false, "false", "")
+		tst.RegisterStringFlag("tls-cert", "", "", "", "")
+		tst.RegisterStringFlag("tls-key", "", "", "", "")
+		tst.MutuallyExclusive("json", "yaml")
+		tst.RequiredTogether("tls-cert", "tls-key")
+		err := tst.Set()
+		if err == nil {
+			_, err = tst.ParseFlags(test.args)
+		}
+		for k := range test.env {
+			os.Unsetenv(k)
+		}
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%v %v: got %q; want %q", test.args, test.env, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%v %v: got no error; want %q", test.args, test.env, test.err)
+		}
+	}
+}
+
+func TestGroupsDefaultConf(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{nil, ""},
+		{[]string{"-json"}, ""},
+		{[]string{"-json", "-yaml"}, "json, yaml: mutually exclusive: json set by flag -json; yaml set by flag -yaml"},
+		{[]string{"-tls-key", "key.pem"}, "tls-key, tls-cert: required together: tls-key set by flag -tls-key; tls-cert not set"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetErrOnMissingConfFile(false)
+		tst.SetUsage(func() {})
+		tst.RegisterBoolFlag("json", "", false, "false", "")
+		tst.RegisterBoolFlag("yaml", "", false, "false", "")
+		tst.RegisterStringFlag("tls-cert", "", "", "", "")
+		tst.RegisterStringFlag("tls-key", "", "", "", "")
+		tst.MutuallyExclusive("json", "yaml")
+		tst.RequiredTogether("tls-cert", "tls-key")
+		// the default configuration's values aren't set
+		err := tst.SetDefaultConf([]byte(`{"json": true, "yaml": true, "tls-cert": "cert.pem"}`), JSON)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		err = tst.Set()
+		if err == nil {
+			_, err = tst.ParseFlags(test.args)
+		}
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%v: got %q; want %q", test.args, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%v: got no error; want %q", test.args, test.err)
+		}
+	}
+}
+
+func TestGroupsWithoutFlags(t *testing.T) {
+	os.Setenv("APP_JSON", "true")
+	os.Setenv("APP_YAML", "true")
+	defer os.Unsetenv("APP_JSON")
+	defer os.Unsetenv("APP_YAML")
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.RegisterBoolEnvVar("json", false)
+	tst.RegisterBoolEnvVar("yaml", false)
+	tst.MutuallyExclusive("json", "yaml")
+	err := tst.Set()
+	want := "json, yaml: mutually exclusive: json set by env var APP_JSON; yaml set by env var APP_YAML"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v; want %q", err, want)
+	}
+}
+
+func TestAddGroup(t *testing.T) {
+	tst := New("app")
+	tst.RegisterBoolFlag("json", "", false, "false", "")
+	tests := []struct {
+		keys []string
+		err  error
+	}{
+		{nil, ErrGroupTooSmall},
+		{[]string{"json"}, ErrGroupTooSmall},
+		{[]string{"json", "yaml"}, SettingNotFoundError{k: "yaml"}},
+	}
+	for _, test := range tests {
+		err := tst.MutuallyExclusive(test.keys...)
+		if err != test.err {
+			t.Errorf("%v: got %v; want %v", test.keys, err, test.err)
+		}
+		err = tst.RequiredTogether(test.keys...)
+		if err != test.err {
+			t.Errorf("%v: got %v; want %v", test.keys, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package contour
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/parser"
+)
+
+// unmarshalHCL returns the HCL configuration b. Its blocks are nested keys,
+// like a JSON configuration's objects, see hclBlocks. If b cannot be parsed,
+// the error has the line and column of the problem.
+func unmarshalHCL(b []byte) (interface{}, error) {
+	var ret interface{}
+	err := hcl.Unmarshal(b, &ret)
+	if err != nil {
+		if perr, ok := err.(*parser.PosError); ok {
+			return nil, fmt.Errorf("line %d, column %d: %s", perr.Pos.Line, perr.Pos.Column, perr.Err)
+		}
+		return nil, err
+	}
+	return hclBlocks(ret), nil
+}
+
+// hclBlocks returns v with its blocks as maps. HCL decodes a block to a list
+// of maps, one for each time the block's key is used, and a labeled block,
+// e.g. profiles "dev" { ... }, to a list with a map of the label to the
+// block. The maps of a block are merged into one, unless they have a key in
+// common, e.g. a block that's repeated, which remains a list of maps. This is
+// done recursively.
+func hclBlocks(v interface{}) interface{} {
+	switch x := v.(type) {
+	case []map[string]interface{}:
+		m := map[string]interface{}{}
+		for _, block := range x {
+			for k, val := range block {
+				if _, ok := m[k]; ok {
+					l := make([]interface{}, len(x))
+					for i := range x {
+						l[i] = hclBlocks(x[i])
+					}
+					return l
+				}
+				m[k] = val
+			}
+		}
+		return hclBlocks(m)
+	case map[string]interface{}:
+		for k, val := range x {
+			x[k] = hclBlocks(val)
+		}
+	case []interface{}:
+		for i := range x {
+			x[i] = hclBlocks(x[i])
+		}
+	}
+	return v
+}
@@ -0,0 +1,94 @@
+package contour
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHCL(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.hcl": {Data: []byte(`name = "app"
+port = 8080
+
+db {
+  host = "db.example.com"
+  pool {
+    max = 10
+  }
+}
+
+server {
+  name = "a"
+}
+
+server {
+  name = "b"
+}
+
+profiles "dev" {
+  name = "app-dev"
+}
+
+profiles "prod" {
+  port = 80
+}
+`)},
+		"bad.hcl": {Data: []byte("port = \n name = {\n")},
+	}
+	tests := []struct {
+		fname   string
+		profile string
+		name    string
+		port    int
+		db      interface{}
+		servers interface{}
+		err     string
+	}{
+		{
+			"app.hcl", "", "app", 8080,
+			map[string]interface{}{"host": "db.example.com", "pool": map[string]interface{}{"max": 10}},
+			[]interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}},
+			"",
+		},
+		{"app.hcl", "dev", "app-dev", 8080, nil, nil, ""},
+		{"app.hcl", "prod", "app", 80, nil, nil, ""},
+		{"bad.hcl", "", "", 0, nil, nil, "bad.hcl: line 2, column 2: Unknown token: 2:2 IDENT name"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetConfFS(fsys)
+		tst.SetConfFilename(test.fname)
+		tst.SetProfile(test.profile)
+		tst.RegisterStringConfFileVar("name", "")
+		tst.RegisterIntConfFileVar("port", 0)
+		tst.RegisterInterfaceConfFileVar("db", nil)
+		tst.RegisterInterfaceConfFileVar("server", nil)
+		err := tst.SetFromConfFile()
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%s %q: got %q; want %q", test.fname, test.profile, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%s %q: got no error; want %q", test.fname, test.profile, test.err)
+			continue
+		}
+		if v := tst.String("name"); v != test.name {
+			t.Errorf("%s %q: name: got %q; want %q", test.fname, test.profile, v, test.name)
+		}
+		if v := tst.Int("port"); v != test.port {
+			t.Errorf("%s %q: port: got %d; want %d", test.fname, test.profile, v, test.port)
+		}
+		if test.db == nil {
+			continue
+		}
+		if v := tst.Get("db"); !reflect.DeepEqual(v, test.db) {
+			t.Errorf("%s %q: db: got %#v; want %#v", test.fname, test.profile, v, test.db)
+		}
+		if v := tst.Get("server"); !reflect.DeepEqual(v, test.servers) {
+			t.Errorf("%s %q: server: got %#v; want %#v", test.fname, test.profile, v, test.servers)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package contour
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// unmarshalINI returns the INI configuration b as a map of its keys. The keys
+// in a section are dotted keys, e.g. the host key in the [db] section is
+// db.host; the keys that precede the first section are top level keys. All of
+// the values are strings.
+func unmarshalINI(b []byte) (map[string]interface{}, error) {
+	f, err := ini.LoadSources(ini.LoadOptions{IgnoreInlineComment: true}, b)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	for _, sec := range f.Sections() {
+		prefix := ""
+		if sec.Name() != ini.DefaultSection {
+			prefix = sec.Name() + "."
+		}
+		for _, k := range sec.Keys() {
+			m[prefix+k.Name()] = k.Value()
+		}
+	}
+	nestProfiles(m)
+	return m, nil
+}
+
+// marshalINI returns m, a map of string values by dotted key, as INI. A key's
+// section is everything before its last dot, e.g. db.host is the host key in
+// the [db] section.
+func marshalINI(m map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	f := ini.Empty()
+	for _, k := range keys {
+		sec, name := ini.DefaultSection, k
+		if i := strings.LastIndex(k, "."); i > 0 {
+			sec, name = k[:i], k[i+1:]
+		}
+		_, err := f.Section(sec).NewKey(name, m[k])
+		if err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	_, err := f.WriteTo(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,77 @@
+package contour
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestINI(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.ini": {Data: []byte(`name = app
+debug = true
+
+[db]
+host = db.example.com
+port = 5432
+max_conns = 9000000000
+
+[profiles.dev]
+name = app-dev
+
+[profiles.dev.db]
+host = localhost
+`)},
+		"bad.ini": {Data: []byte("[db]\nport = many\n")},
+	}
+	tests := []struct {
+		fname   string
+		profile string
+		name    string
+		debug   bool
+		host    string
+		port    int
+		conns   int64
+		err     string
+	}{
+		{"app.ini", "", "app", true, "db.example.com", 5432, 9000000000, ""},
+		{"app.ini", "dev", "app-dev", true, "localhost", 5432, 9000000000, ""},
+		{"bad.ini", "", "", false, "", 0, 0, `configuration file var db.port: cannot parse "many" as int`},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetConfFS(fsys)
+		tst.SetConfFilename(test.fname)
+		tst.SetProfile(test.profile)
+		tst.RegisterStringConfFileVar("name", "")
+		tst.RegisterBoolConfFileVar("debug", false)
+		tst.RegisterStringConfFileVar("db.host", "")
+		tst.RegisterIntConfFileVar("db.port", 0)
+		tst.RegisterInt64ConfFileVar("db.max_conns", 0)
+		err := tst.SetFromConfFile()
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%s %q: got %q; want %q", test.fname, test.profile, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%s %q: got no error; want %q", test.fname, test.profile, test.err)
+			continue
+		}
+		if v := tst.String("name"); v != test.name {
+			t.Errorf("%s %q: name: got %q; want %q", test.fname, test.profile, v, test.name)
+		}
+		if v := tst.Bool("debug"); v != test.debug {
+			t.Errorf("%s %q: debug: got %t; want %t", test.fname, test.profile, v, test.debug)
+		}
+		if v := tst.String("db.host"); v != test.host {
+			t.Errorf("%s %q: db.host: got %q; want %q", test.fname, test.profile, v, test.host)
+		}
+		if v := tst.Int("db.port"); v != test.port {
+			t.Errorf("%s %q: db.port: got %d; want %d", test.fname, test.profile, v, test.port)
+		}
+		if v := tst.Int64("db.max_conns"); v != test.conns {
+			t.Errorf("%s %q: db.max_conns: got %d; want %d", test.fname, test.profile, v, test.conns)
+		}
+	}
+}
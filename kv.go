@@ -0,0 +1,273 @@
+package contour
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long KVSource.Watch waits before watching the store again after the
+// store returned an error.
+const kvRetryDelay = time.Second
+
+// KVPair is a key and its value in a KV store.
+type KVPair struct {
+	Key   string
+	Value []byte
+	// The store's revision when the key was last modified.
+	Revision uint64
+}
+
+// KVStore is a key/value store, e.g. Consul's or etcd's, whose keys are
+// hierarchical, e.g. app/db/host, and whose changes have revisions.
+type KVStore interface {
+	// List returns the pairs whose keys start with prefix, sorted by key,
+	// along with the store's current revision of them.
+	List(prefix string) ([]KVPair, uint64, error)
+	// Watch blocks until a key that starts with prefix has been modified,
+	// added, or deleted, after revision rev, or ctx is done. It returns the
+	// store's revision of the keys after the change.
+	Watch(ctx context.Context, prefix string, rev uint64) (uint64, error)
+}
+
+// KVSource is a Source whose values are the keys in a KV store that start with
+// a prefix. The rest of a key, with any slashes replaced by dots, is the name
+// of the setting, e.g. with the prefix app/, the value of the key app/db/host
+// is the db.host setting's value. The origin of a value is its key and
+// revision, e.g. "kv app/db/host (revision 12)". Like any source that's added
+// to settings, its place in settings' order of precedence is the SettingType
+// that AddSource returns, see SetPrecedence.
+type KVSource struct {
+	mu     sync.Mutex
+	store  KVStore
+	prefix string
+	rev    uint64
+}
+
+// NewKVSource returns a KVSource for store's keys that start with prefix.
+func NewKVSource(store KVStore, prefix string) *KVSource {
+	return &KVSource{store: store, prefix: prefix}
+}
+
+// Name returns the source's name: kv and its prefix, e.g. "kv app/".
+func (k *KVSource) Name() string { return "kv " + k.prefix }
+
+// Revision returns the store's revision of the source's keys when they were
+// last loaded; 0 if they haven't been.
+func (k *KVSource) Revision() uint64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.rev
+}
+
+// Load returns the values of the source's keys, by setting name. Keys without
+// a setting name, e.g. the prefix itself or a folder, are skipped.
+func (k *KVSource) Load() (map[string]SourceValue, error) {
+	pairs, rev, err := k.store.List(k.prefix)
+	if err != nil {
+		return nil, err
+	}
+	vals := map[string]SourceValue{}
+	for _, p := range pairs {
+		name := strings.Trim(strings.TrimPrefix(p.Key, k.prefix), "/")
+		if name == "" || strings.HasSuffix(p.Key, "/") {
+			continue
+		}
+		name = strings.Replace(name, "/", ".", -1)
+		vals[name] = SourceValue{Value: string(p.Value), Origin: fmt.Sprintf("kv %s (revision %d)", p.Key, p.Revision)}
+	}
+	k.mu.Lock()
+	k.rev = rev
+	k.mu.Unlock()
+	return vals, nil
+}
+
+// Watch watches the store for changes to the source's keys until ctx is done,
+// which is what it returns. Each time they change, the source's values are
+// reloaded by s, see Settings.ReloadSource, and fn, if it isn't nil, is called
+// with the error, if any. If watching the store, or reloading the values,
+// fails, fn is called with the error and the store is watched again after a
+// second. The source must have been added to s and s must have been set.
+// Watch blocks, so it's usually run in its own goroutine.
+func (k *KVSource) Watch(ctx context.Context, s *Settings, fn func(error)) error {
+	for {
+		_, err := k.store.Watch(ctx, k.prefix, k.Revision())
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if fn != nil {
+				fn(fmt.Errorf("%s: watch: %w", k.Name(), err))
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(kvRetryDelay):
+			}
+			continue
+		}
+		err = s.ReloadSource(k.Name())
+		if fn != nil {
+			fn(err)
+		}
+		if err == nil {
+			continue
+		}
+		// if the values weren't loaded, the revision hasn't changed and the
+		// store would be watched from it again right away.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(kvRetryDelay):
+		}
+	}
+}
+
+// LocalKV is an in-process KVStore, optionally backed by a file, that can
+// stand in for a KV store, e.g. in tests or when there's no store to connect
+// to. A LocalKV's file is a JSON object of keys and their values, e.g.
+//    {"app/db/host": "localhost", "app/db/port": "5432"}
+// Each Put or Delete is a new revision and, if the LocalKV has a file, it
+// rewrites the file.
+type LocalKV struct {
+	mu      sync.Mutex
+	fname   string
+	pairs   map[string]KVPair
+	deleted map[string]uint64 // the revision each deleted key was deleted in
+	rev     uint64
+	changed chan struct{} // closed, and replaced, on each change
+}
+
+// NewLocalKV returns an empty LocalKV without a file.
+func NewLocalKV() *LocalKV {
+	return &LocalKV{
+		pairs:   map[string]KVPair{},
+		deleted: map[string]uint64{},
+		changed: make(chan struct{}),
+	}
+}
+
+// OpenLocalKV returns a LocalKV backed by the file fname. If the file exists,
+// its keys are the store's first revision; if it doesn't, the store is empty
+// and the file is created by the first change.
+func OpenLocalKV(fname string) (*LocalKV, error) {
+	l := NewLocalKV()
+	l.fname = fname
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return l, nil
+		}
+		return nil, err
+	}
+	var m map[string]string
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fname, err)
+	}
+	if len(m) > 0 {
+		l.rev = 1
+	}
+	for k, v := range m {
+		l.pairs[k] = KVPair{Key: k, Value: []byte(v), Revision: l.rev}
+	}
+	return l, nil
+}
+
+// Put sets key's value.
+func (l *LocalKV) Put(key, value string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rev++
+	l.pairs[key] = KVPair{Key: key, Value: []byte(value), Revision: l.rev}
+	delete(l.deleted, key)
+	return l.change()
+}
+
+// Delete deletes key; deleting a key that doesn't exist does nothing.
+func (l *LocalKV) Delete(key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.pairs[key]; !ok {
+		return nil
+	}
+	l.rev++
+	delete(l.pairs, key)
+	l.deleted[key] = l.rev
+	return l.change()
+}
+
+// change notifies the watchers of a change and writes the file, if there is
+// one. This assumes the lock has been obtained.
+func (l *LocalKV) change() error {
+	close(l.changed)
+	l.changed = make(chan struct{})
+	if l.fname == "" {
+		return nil
+	}
+	m := make(map[string]string, len(l.pairs))
+	for k, v := range l.pairs {
+		m[k] = string(v.Value)
+	}
+	b, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(l.fname, b, 0644)
+}
+
+// List returns the pairs whose keys start with prefix, sorted by key, and the
+// store's current revision.
+func (l *LocalKV) List(prefix string) ([]KVPair, uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var pairs []KVPair
+	for k, v := range l.pairs {
+		if strings.HasPrefix(k, prefix) {
+			pairs = append(pairs, v)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, l.rev, nil
+}
+
+// Watch blocks until a key that starts with prefix changes after revision
+// rev, or ctx is done; it returns the store's current revision.
+func (l *LocalKV) Watch(ctx context.Context, prefix string, rev uint64) (uint64, error) {
+	for {
+		l.mu.Lock()
+		changed := l.changedSince(prefix, rev)
+		cur, ch := l.rev, l.changed
+		l.mu.Unlock()
+		if changed {
+			return cur, nil
+		}
+		select {
+		case <-ctx.Done():
+			return cur, ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+// changedSince returns if a key that starts with prefix has changed after
+// revision rev. This assumes the lock has been obtained.
+func (l *LocalKV) changedSince(prefix string, rev uint64) bool {
+	for k, v := range l.pairs {
+		if v.Revision > rev && strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	for k, r := range l.deleted {
+		if r > rev && strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
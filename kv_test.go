@@ -0,0 +1,158 @@
+package contour
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLocalKV(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "kv.json")
+	kv, err := OpenLocalKV(fname)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	kv.Put("app/port", "9090")
+	kv.Put("app/db/host", "db.example.com")
+	kv.Put("other/port", "1")
+	kv.Delete("other/port")
+	pairs, rev, err := kv.List("app/")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []KVPair{
+		{Key: "app/db/host", Value: []byte("db.example.com"), Revision: 2},
+		{Key: "app/port", Value: []byte("9090"), Revision: 1},
+	}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("got %v; want %v", pairs, expected)
+	}
+	if rev != 4 {
+		t.Errorf("revision: got %d; want 4", rev)
+	}
+
+	// the file has the keys
+	kv, err = OpenLocalKV(fname)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	pairs, rev, _ = kv.List("")
+	if len(pairs) != 2 || rev != 1 {
+		t.Errorf("reopened: got %v at revision %d; want 2 keys at revision 1", pairs, rev)
+	}
+
+	// watch returns once a key with the prefix changes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	_, err = kv.Watch(ctx, "app/", rev)
+	cancel()
+	if err != context.DeadlineExceeded {
+		t.Errorf("watch: got %v; want %v", err, context.DeadlineExceeded)
+	}
+	go func() {
+		kv.Put("other/port", "2")
+		kv.Delete("app/port")
+	}()
+	rev, err = kv.Watch(context.Background(), "app/", rev)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rev != 3 {
+		t.Errorf("watch: got revision %d; want 3", rev)
+	}
+}
+
+func TestKVSource(t *testing.T) {
+	os.Setenv("APP_LOG", "debug")
+	defer os.Unsetenv("APP_LOG")
+	kv := NewLocalKV()
+	kv.Put("app/port", "9090")
+	kv.Put("app/db/host", "db.example.com")
+	kv.Put("app/log", "info")
+	kv.Put("app/", "")
+	src := NewKVSource(kv, "app/")
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.AddSource(src)
+	tst.RegisterIntConfFileVar("port", 8080)
+	tst.RegisterStringConfFileVar("db.host", "localhost")
+	tst.RegisterStringEnvVar("log", "warn")
+	err := tst.Set()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v := tst.Int("port"); v != 9090 {
+		t.Errorf("port: got %d; want 9090", v)
+	}
+	if v := tst.String("db.host"); v != "db.example.com" {
+		t.Errorf("db.host: got %q; want db.example.com", v)
+	}
+	if o := tst.Origin("port"); o != "kv app/port (revision 1)" {
+		t.Errorf("origin: got %q; want %q", o, "kv app/port (revision 1)")
+	}
+	if v := tst.String("log"); v != "debug" {
+		t.Errorf("log: got %q; want debug", v)
+	}
+	if v := src.Revision(); v != 4 {
+		t.Errorf("revision: got %d; want 4", v)
+	}
+
+	// watch reloads the changes; the env var still takes precedence
+	ctx, cancel := context.WithCancel(context.Background())
+	reloaded := make(chan error)
+	done := make(chan error)
+	go func() { done <- src.Watch(ctx, tst, func(err error) { reloaded <- err }) }()
+	kv.Put("app/port", "7070")
+	kv.Put("app/log", "error")
+	for tst.Int("port") != 7070 || src.Revision() != 6 {
+		select {
+		case err = <-reloaded:
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the reload")
+		}
+	}
+	if o := tst.Origin("port"); o != "kv app/port (revision 5)" {
+		t.Errorf("origin: got %q; want %q", o, "kv app/port (revision 5)")
+	}
+	if v := tst.String("log"); v != "debug" {
+		t.Errorf("log: got %q; want debug", v)
+	}
+	cancel()
+	if err = <-done; err != context.Canceled {
+		t.Errorf("watch: got %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestKVSourceWatchReloadError(t *testing.T) {
+	kv := NewLocalKV()
+	kv.Put("app/port", "9090")
+	src := NewKVSource(kv, "app/")
+	// the source wasn't added, so reloading fails without loading it
+	tst := New("app")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var n int
+	err := src.Watch(ctx, tst, func(err error) {
+		n++
+		if err != (SourceNotFoundError{name: src.Name()}) {
+			t.Errorf("got %v; want %v", err, SourceNotFoundError{name: src.Name()})
+		}
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("watch: got %v; want %v", err, context.DeadlineExceeded)
+	}
+	if n != 1 {
+		t.Errorf("got %d failed reloads; want 1", n)
+	}
+}
@@ -0,0 +1,146 @@
+package contour
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The usage of the built-in override flag.
+const overrideUsage = "override a setting, key=value; can be repeated"
+
+// overrideValue is the flag.Value for the override flag. Each time the flag is
+// used, its key=value is appended.
+type overrideValue []string
+
+func (o *overrideValue) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("%q is not of the form key=value", s)
+	}
+	*o = append(*o, s)
+	return nil
+}
+
+func (o *overrideValue) String() string {
+	if o == nil {
+		return ""
+	}
+	return strings.Join(*o, ",")
+}
+
+// OverrideFlag returns the name of settings' built-in override flag; an empty
+// string is returned if settings doesn't have one.
+func (s *Settings) OverrideFlag() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.overrideFlag
+}
+
+// SetOverrideFlag sets the name of settings' built-in override flag, e.g. set
+// for --set, or -set if settings isn't set to use POSIX flags. The flag's
+// value is key=value and it can be used more than once, e.g.:
+//    app --set db.host=db.example.com --set timeout=30
+// Each key is the name, or alias, of a configuration file, environment
+// variable, or flag setting, whose value is set to the value, converted to
+// the setting's data type. This allows any setting to be overridden for a
+// single run, even a setting that can only be set by the configuration file.
+// A setting's own flag takes precedence over the override flag. The origin of
+// an overridden setting's value is the override flag, e.g. "flag --set
+// db.host".
+//
+// If a key doesn't exist, a SettingNotFoundError is returned by ParseFlags; if
+// it is a Core setting, a CoreUpdateError; if it is a Basic setting, an
+// UpdateError; and if the value cannot be converted, a ParseError.
+//
+// If name is empty, settings doesn't have an override flag, which is the
+// default. If settings has a setting, short flag, or alias with the name, the
+// flag isn't added. If the flags have already been parsed, an ErrFlagsParsed
+// is returned.
+func (s *Settings) SetOverrideFlag(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flagsParsed {
+		return ErrFlagsParsed
+	}
+	s.overrideFlag = name
+	if name != "" {
+		s.useFlags = true
+	}
+	return nil
+}
+
+// hasOverrideFlag returns if settings has the override flag: it has been set
+// and there isn't a setting, short flag, or alias with its name. This assumes
+// the lock has been obtained.
+func (s *Settings) hasOverrideFlag() bool {
+	if s.overrideFlag == "" {
+		return false
+	}
+	if _, ok := s.settings[s.overrideFlag]; ok {
+		return false
+	}
+	if _, ok := s.shortFlags[s.overrideFlag]; ok {
+		return false
+	}
+	_, ok := s.aliases[s.overrideFlag]
+	return !ok
+}
+
+// setOverrideFlag adds the override flag to the flagSet, if settings has one.
+// This assumes the lock has been obtained.
+func (s *Settings) setOverrideFlag() {
+	if !s.hasOverrideFlag() {
+		return
+	}
+	s.flagSet.Var(&overrideValue{}, s.overrideFlag, overrideUsage)
+}
+
+// applyOverrides sets the settings that were overridden by the override flag,
+// in the order that they were overridden. A setting whose flag was parsed
+// isn't overridden. This assumes the lock has been obtained and that the
+// flags have been visited.
+func (s *Settings) applyOverrides() error {
+	if !s.hasOverrideFlag() {
+		return nil
+	}
+	f := s.flagSet.Lookup(s.overrideFlag)
+	name := s.longFlag(s.overrideFlag)
+	for _, kv := range *f.Value.(*overrideValue) {
+		k, tmp, _ := strings.Cut(kv, "=")
+		if n, ok := s.aliases[k]; ok {
+			k = n
+		}
+		v, ok := s.settings[k]
+		if !ok {
+			return SettingNotFoundError{k: k}
+		}
+		if v.IsCore {
+			return CoreUpdateError{k: k}
+		}
+		if !v.IsConfFileVar && !v.IsEnvVar && !v.IsFlag {
+			return UpdateError{typ: Basic.String(), k: k}
+		}
+		if s.WasVisited(k) || s.overridden(v, Flag) {
+			continue
+		}
+		val, err := s.parseValue(Flag, name+" "+k, k, tmp)
+		if err != nil {
+			return err
+		}
+		v.Value = val
+		v.SetBy = Flag
+		v.Origin = "flag " + name + " " + k
+		if src, ok := s.responseFileFlags[s.overrideFlag]; ok {
+			v.Origin = "response file " + src + " (" + v.Origin + ")"
+		}
+		s.settings[k] = v
+	}
+	return nil
+}
+
+// OverrideFlag returns the name of the standard settings' built-in override
+// flag.
+func OverrideFlag() string { return std.OverrideFlag() }
+
+// SetOverrideFlag sets the name of the standard settings' built-in override
+// flag. See Settings.SetOverrideFlag.
+func SetOverrideFlag(name string) error { return std.SetOverrideFlag(name) }
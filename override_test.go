@@ -0,0 +1,109 @@
+package contour
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestOverrideFlag(t *testing.T) {
+	tests := []struct {
+		args    []string
+		host    string
+		timeout int
+		level   string
+		origin  string
+		err     string
+	}{
+		{nil, "localhost", 10, "info", "default", ""},
+		{[]string{"--set", "db.host=db.example.com"}, "db.example.com", 10, "info", "flag --set db.host", ""},
+		{[]string{"--set", "db.host=a=b", "--set=timeout=30"}, "a=b", 30, "info", "flag --set db.host", ""},
+		{[]string{"--set", "db.host=a", "--set", "db.host=b"}, "b", 10, "info", "flag --set db.host", ""},
+		{[]string{"--set", "dbhost=a"}, "a", 10, "info", "flag --set db.host", ""},
+		{[]string{"--set", "level=debug", "--level", "warn"}, "localhost", 10, "warn", "default", ""},
+		{[]string{"--set", "level=debug"}, "localhost", 10, "debug", "default", ""},
+		{[]string{"--set", "timeout=soon"}, "", 0, "", "", `flag --set timeout: cannot parse "soon" as int`},
+		{[]string{"--set", "missing=1"}, "", 0, "", "", "missing: setting not found"},
+		{[]string{"--set", "version=2"}, "", 0, "", "", "version: core settings cannot be updated"},
+		{[]string{"--set", "app=x"}, "", 0, "", "", "app: basic settings cannot be updated"},
+		{[]string{"--set", "db.host"}, "", 0, "", "", `parse of command-line arguments failed: invalid value "db.host" for flag --set: "db.host" is not of the form key=value`},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetErrOnMissingConfFile(false)
+		tst.SetPOSIXFlags(true)
+		tst.SetUsage(func() {})
+		tst.SetOverrideFlag("set")
+		tst.RegisterStringConfFileVar("db.host", "localhost")
+		tst.RegisterIntEnvVar("timeout", 10)
+		tst.RegisterStringFlag("level", "", "info", "info", "")
+		tst.AddStringCore("version", "1.0")
+		tst.AddString("app", "")
+		tst.AddAlias("db.host", "dbhost")
+		err := tst.Set()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		_, err = tst.ParseFlags(test.args)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%v: got %q; want %q", test.args, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%v: got no error; want %q", test.args, test.err)
+			continue
+		}
+		if v := tst.String("db.host"); v != test.host {
+			t.Errorf("%v: db.host: got %q; want %q", test.args, v, test.host)
+		}
+		if v := tst.Int("timeout"); v != test.timeout {
+			t.Errorf("%v: timeout: got %d; want %d", test.args, v, test.timeout)
+		}
+		if v := tst.String("level"); v != test.level {
+			t.Errorf("%v: level: got %q; want %q", test.args, v, test.level)
+		}
+		if o := tst.Origin("db.host"); o != test.origin {
+			t.Errorf("%v: origin: got %q; want %q", test.args, o, test.origin)
+		}
+	}
+}
+
+func TestOverrideFlagPrecedence(t *testing.T) {
+	os.Setenv("APP_TIMEOUT", "20")
+	defer os.Unsetenv("APP_TIMEOUT")
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.SetUsage(func() {})
+	tst.SetOverrideFlag("set")
+	tst.RegisterIntEnvVar("timeout", 10)
+	tst.RegisterInterfaceConfFileVar("labels", nil)
+	tst.Set()
+	_, err := tst.ParseFlags([]string{"-set", "timeout=30", "-set", `labels={"env": "prod"}`})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v := tst.Int("timeout"); v != 30 {
+		t.Errorf("timeout: got %d; want 30", v)
+	}
+	if o := tst.Origin("timeout"); o != "flag -set timeout" {
+		t.Errorf("origin: got %q; want %q", o, "flag -set timeout")
+	}
+	if v := tst.Interface("labels"); !reflect.DeepEqual(v, map[string]interface{}{"env": "prod"}) {
+		t.Errorf("labels: got %#v; want map[env:prod]", v)
+	}
+}
+
+func TestOverrideFlagName(t *testing.T) {
+	tst := New("app")
+	tst.SetOverrideFlag("set")
+	tst.RegisterBoolFlag("set", "", false, "false", "")
+	if tst.hasOverrideFlag() {
+		t.Error("got override flag; want none when a setting has its name")
+	}
+	tst.ParseFlags(nil)
+	if err := tst.SetOverrideFlag("override"); err != ErrFlagsParsed {
+		t.Errorf("got %v; want %v", err, ErrFlagsParsed)
+	}
+}
@@ -0,0 +1,180 @@
+package contour
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// parsePOSIXFlags parses args using POSIX/GNU conventions instead of the flag
+// package's:
+//    --name, --name=value, or --name value for a flag's name
+//    -s, -svalue, -s=value, or -s value for a flag's short name
+//    -abc is the same as -a -b -c when a and b are bool flags; the first
+//      non-bool flag consumes the rest of the arg as its value
+//    flags and non-flag args may be interspersed
+//    -- ends flag parsing; everything after it is a non-flag arg
+//    - by itself is a non-flag arg
+//
+// The values are set on settings' flagSet so that the flagSet's visited
+// information is the same as if the flag package had parsed the args. The
+// non-flag args are returned, in order. This assumes that the lock has been
+// obtained and that setFlags has been called.
+func (s *Settings) parsePOSIXFlags(args []string) ([]string, error) {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			return append(rest, args[i+1:]...), nil
+		case strings.HasPrefix(a, "--"):
+			name, val, hasVal := strings.Cut(a[2:], "=")
+			f := s.lookupLongFlag(name)
+			if f == nil {
+				return nil, s.posixFlagErr(name, fmt.Errorf("flag provided but not defined: --%s", name))
+			}
+			if !hasVal {
+				if isBoolFlag(f) {
+					val = "true"
+				} else {
+					if i+1 >= len(args) {
+						return nil, s.posixFlagErr("", fmt.Errorf("flag needs an argument: --%s", name))
+					}
+					i++
+					val = args[i]
+				}
+			}
+			err := s.flagSet.Set(f.Name, val)
+			if err != nil {
+				return nil, s.posixFlagErr("", fmt.Errorf("invalid value %q for flag --%s: %s", val, name, err))
+			}
+		case strings.HasPrefix(a, "-") && a != "-":
+			// one or more short flags
+			for j := 1; j < len(a); {
+				r, n := utf8.DecodeRuneInString(a[j:])
+				name := string(r)
+				j += n
+				f := s.lookupShortFlag(name)
+				if f == nil {
+					return nil, s.posixFlagErr(name, fmt.Errorf("flag provided but not defined: -%s", name))
+				}
+				var val string
+				if isBoolFlag(f) {
+					// a bool flag only has a value if it's explicitly set using =
+					val = "true"
+					if strings.HasPrefix(a[j:], "=") {
+						val = a[j+1:]
+						j = len(a)
+					}
+				} else {
+					// the rest of the arg, if any, is the value
+					val = strings.TrimPrefix(a[j:], "=")
+					if j == len(a) {
+						if i+1 >= len(args) {
+							return nil, s.posixFlagErr("", fmt.Errorf("flag needs an argument: -%s", name))
+						}
+						i++
+						val = args[i]
+					}
+					j = len(a)
+				}
+				err := s.flagSet.Set(f.Name, val)
+				if err != nil {
+					return nil, s.posixFlagErr("", fmt.Errorf("invalid value %q for flag -%s: %s", val, name, err))
+				}
+			}
+		default:
+			// if there are commands, the first non-flag arg is the command
+			// and the rest of the args are the command's.
+			if len(s.commands) > 0 {
+				return append(rest, args[i:]...), nil
+			}
+			rest = append(rest, a)
+		}
+	}
+	return rest, nil
+}
+
+// lookupLongFlag returns the flag for name if it is a flag's name. Short
+// names are not long flags, even though they are in the flagSet.
+func (s *Settings) lookupLongFlag(name string) *flag.Flag {
+	if _, ok := s.shortFlags[name]; ok {
+		if _, ok := s.settings[name]; !ok {
+			return nil
+		}
+	}
+	return s.flagSet.Lookup(name)
+}
+
+// lookupShortFlag returns the flag for name if it is a flag's short name.
+func (s *Settings) lookupShortFlag(name string) *flag.Flag {
+	if _, ok := s.shortFlags[name]; !ok {
+		return nil
+	}
+	return s.flagSet.Lookup(name)
+}
+
+// posixFlagErr handles a parse error the same way the flag package does: the
+// error and the usage are written to the flagSet's output. If the undefined
+// flag was a request for help, flag.ErrHelp is returned instead.
+func (s *Settings) posixFlagErr(name string, err error) error {
+	if name == "help" || name == "h" {
+		s.usage()
+		return flag.ErrHelp
+	}
+	fmt.Fprintln(s.flagSet.Output(), err)
+	s.usage()
+	return err
+}
+
+// usage calls the flagSet's usage func. If it has been set to nil, the
+// generated usage is printed.
+func (s *Settings) usage() {
+	if s.flagSet.Usage != nil {
+		s.flagSet.Usage()
+		return
+	}
+	s.defaultUsage()
+}
+
+// isBoolFlag returns if f is a bool flag, i.e. a flag that doesn't need a
+// value.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// POSIXFlags returns if settings parses flags using POSIX/GNU conventions.
+func (s *Settings) POSIXFlags() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.posixFlags
+}
+
+// SetPOSIXFlags sets if settings should parse flags using POSIX/GNU
+// conventions instead of the flag package's conventions. In POSIX mode, a
+// flag's name must be preceded by two dashes, --name, and its short name by
+// one, -s. Short bool flags can be combined, -vxf, a short flag's value may
+// be attached to it, -p8080, and flags may follow non-flag args. The
+// non-flag args are returned by ParseFlags in the order they were found.
+//
+// If the flags have already been parsed, an ErrFlagsParsed is returned.
+func (s *Settings) SetPOSIXFlags(b bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flagsParsed {
+		return ErrFlagsParsed
+	}
+	s.posixFlags = b
+	return nil
+}
+
+// POSIXFlags returns if the standard settings parses flags using POSIX/GNU
+// conventions.
+func POSIXFlags() bool { return std.POSIXFlags() }
+
+// SetPOSIXFlags sets if the standard settings should parse flags using
+// POSIX/GNU conventions instead of the flag package's conventions. If the
+// flags have already been parsed, an ErrFlagsParsed is returned.
+func SetPOSIXFlags(b bool) error { return std.SetPOSIXFlags(b) }
@@ -0,0 +1,122 @@
+package contour
+
+import (
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestParsePOSIXFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected map[string]interface{}
+		visited  []string
+		rest     []string
+		err      string
+	}{
+		{"long", []string{"--flagint=11", "--flagstring", "updated", "--flagbool-tst"},
+			map[string]interface{}{"flagint": 11, "flagstring": "updated", "flagbool-tst": true},
+			[]string{"flagbool-tst", "flagint", "flagstring"}, nil, ""},
+		{"long bool value", []string{"--flagbool=no"},
+			map[string]interface{}{"flagbool": false},
+			[]string{"flagbool"}, nil, ""},
+		{"short", []string{"-i", "11", "-s", "updated"},
+			map[string]interface{}{"flagint": 11, "flagstring": "updated"},
+			[]string{"flagint", "flagstring"}, nil, ""},
+		{"attached short values", []string{"-i8080", "-s=updated"},
+			map[string]interface{}{"flagint": 8080, "flagstring": "updated"},
+			[]string{"flagint", "flagstring"}, nil, ""},
+		{"combined", []string{"-bi8080"},
+			map[string]interface{}{"flagbool": true, "flagint": 8080},
+			[]string{"flagbool", "flagint"}, nil, ""},
+		{"combined with separate value", []string{"-bs", "updated"},
+			map[string]interface{}{"flagbool": true, "flagstring": "updated"},
+			[]string{"flagbool", "flagstring"}, nil, ""},
+		{"short bool value", []string{"-b=false"},
+			map[string]interface{}{"flagbool": false},
+			[]string{"flagbool"}, nil, ""},
+		{"interspersed", []string{"cmd", "--flagint", "11", "arg", "-b", "last"},
+			map[string]interface{}{"flagint": 11, "flagbool": true},
+			[]string{"flagbool", "flagint"}, []string{"cmd", "arg", "last"}, ""},
+		{"terminator", []string{"-i", "11", "--", "-b", "--flagint=12"},
+			map[string]interface{}{"flagint": 11, "flagbool": true},
+			[]string{"flagint"}, []string{"-b", "--flagint=12"}, ""},
+		{"dash", []string{"-", "-i", "1"},
+			map[string]interface{}{"flagint": 1},
+			[]string{"flagint"}, []string{"-"}, ""},
+		{"negative value", []string{"-i", "-1"},
+			map[string]interface{}{"flagint": -1},
+			[]string{"flagint"}, nil, ""},
+		{"long name with one dash", []string{"-flagint=11"}, nil, nil, nil, "parse of command-line arguments failed: flag provided but not defined: -f"},
+		{"short name with two dashes", []string{"--i=11"}, nil, nil, nil, "parse of command-line arguments failed: flag provided but not defined: --i"},
+		{"undefined", []string{"--nope"}, nil, nil, nil, "parse of command-line arguments failed: flag provided but not defined: --nope"},
+		{"missing value", []string{"--flagint"}, nil, nil, nil, "parse of command-line arguments failed: flag needs an argument: --flagint"},
+		{"missing short value", []string{"-bi"}, nil, nil, nil, "parse of command-line arguments failed: flag needs an argument: -i"},
+		{"invalid value", []string{"-i", "x"}, nil, nil, nil, `parse of command-line arguments failed: invalid value "x" for flag -i: parse error`},
+		{"help", []string{"--help"}, nil, nil, nil, "parse of command-line arguments failed: " + flag.ErrHelp.Error()},
+	}
+	for _, test := range tests {
+		tst := newTestSettings()
+		tst.useFlags = true
+		tst.SetPOSIXFlags(true)
+		tst.flagSet.SetOutput(ioutil.Discard)
+		rest, err := tst.ParseFlags(test.args)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%s: got %q; want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%s: got no error; want %q", test.name, test.err)
+			continue
+		}
+		if !reflect.DeepEqual(rest, test.rest) {
+			t.Errorf("%s: args: got %q; want %q", test.name, rest, test.rest)
+		}
+		if !reflect.DeepEqual(tst.Visited(), test.visited) {
+			t.Errorf("%s: visited: got %v; want %v", test.name, tst.Visited(), test.visited)
+		}
+		for k, v := range test.expected {
+			if got := tst.Get(k); !reflect.DeepEqual(got, v) {
+				// Get returns pointers for parsed flags
+				switch x := got.(type) {
+				case *bool:
+					got = *x
+				case *int:
+					got = *x
+				case *string:
+					got = *x
+				}
+				if !reflect.DeepEqual(got, v) {
+					t.Errorf("%s: %s: got %v; want %v", test.name, k, got, v)
+				}
+			}
+		}
+	}
+}
+
+func TestSetPOSIXFlags(t *testing.T) {
+	tst := newTestSettings()
+	tst.useFlags = true
+	if tst.POSIXFlags() {
+		t.Error("POSIXFlags: got true; want false")
+	}
+	err := tst.SetPOSIXFlags(true)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if !tst.POSIXFlags() {
+		t.Error("POSIXFlags: got false; want true")
+	}
+	_, err = tst.ParseFlags([]string{"-b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = tst.SetPOSIXFlags(false)
+	if err != ErrFlagsParsed {
+		t.Errorf("got %v; want %s", err, ErrFlagsParsed)
+	}
+}
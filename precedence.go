@@ -0,0 +1,194 @@
+package contour
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSourcesSet occurs when settings' precedence is changed after settings
+// has been set from one of its sources.
+var ErrSourcesSet = errors.New("settings have already been set from a source")
+
+// The default order of precedence of settings' sources, from lowest to
+// highest. The sources that have been added to settings are right after the
+// configuration file.
+var defaultPrecedence = []SettingType{ConfFileVar, EnvVar, Flag}
+
+// firstSourceType is the SettingType of the first source that's added to a
+// settings, see AddSource; each source after it has the next one.
+const firstSourceType SettingType = 1 << 8
+
+// PrecedenceError occurs when an order of precedence doesn't have each of the
+// configuration file, environment variable, and flag sources exactly once, or
+// has a source that hasn't been added to settings or has one more than once.
+type PrecedenceError struct {
+	types []SettingType
+}
+
+func (e PrecedenceError) Error() string {
+	return fmt.Sprintf("%v: invalid precedence: must have each of %s, %s, and %s once", e.types, ConfFileVar, EnvVar, Flag)
+}
+
+// Precedence returns the order of precedence of settings' sources, from
+// lowest to highest, including the sources that have been added to it.
+func (s *Settings) Precedence() []SettingType {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]SettingType(nil), s.sourcePrecedence()...)
+}
+
+// SetPrecedence sets the order of precedence of settings' sources, from lowest
+// to highest: types must have each of ConfFileVar, EnvVar, and Flag exactly
+// once, and may have each of the SettingTypes of the sources that have been
+// added to settings, see AddSource, once; otherwise a PrecedenceError is
+// returned. The default is ConfFileVar, EnvVar, Flag: an environment variable
+// overrides the configuration file and a flag overrides both. The added
+// sources that aren't in types are right after the configuration file, in the
+// order they were added. For example, for a configuration file that must
+// override environment variables, but not flags:
+//    s.SetPrecedence(contour.EnvVar, contour.ConfFileVar, contour.Flag)
+// or for a source, e.g. a settings table, that must override environment
+// variables, but not flags:
+//    table, _ := s.AddSource(src)
+//    s.SetPrecedence(contour.ConfFileVar, contour.EnvVar, table, contour.Flag)
+//
+// Set processes the sources in the order of precedence, so that each one
+// overrides the ones before it. Flags are usually parsed after Set; if flags
+// don't have the highest precedence, a flag doesn't override a setting that
+// was set by a source with higher precedence, e.g. its environment variable.
+// Likewise, if the flags are parsed before Set, a source with higher
+// precedence than flags overrides them.
+//
+// If settings has already been set from any of its sources, an ErrSourcesSet
+// is returned.
+func (s *Settings) SetPrecedence(types ...SettingType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.confFileVarsSet || s.envVarsSet || s.flagsParsed || s.sourcesSet {
+		return ErrSourcesSet
+	}
+	n := map[SettingType]int{}
+	for _, t := range types {
+		if !s.isSourceType(t) && t != ConfFileVar && t != EnvVar && t != Flag {
+			return PrecedenceError{types: types}
+		}
+		n[t]++
+		if n[t] > 1 {
+			return PrecedenceError{types: types}
+		}
+	}
+	for _, typ := range defaultPrecedence {
+		if n[typ] != 1 {
+			return PrecedenceError{types: types}
+		}
+	}
+	s.precedence = append([]SettingType(nil), types...)
+	return nil
+}
+
+// sourcePrecedence returns the order of precedence of settings' sources, with
+// the added sources that aren't in it right after the configuration file.
+// This assumes the lock has been obtained.
+func (s *Settings) sourcePrecedence() []SettingType {
+	prec := s.precedence
+	if prec == nil {
+		prec = defaultPrecedence
+	}
+	if len(s.sources) == 0 {
+		return prec
+	}
+	var missing []SettingType
+	for i := range s.sources {
+		typ := firstSourceType + SettingType(i)
+		if !hasType(prec, typ) {
+			missing = append(missing, typ)
+		}
+	}
+	if len(missing) == 0 {
+		return prec
+	}
+	p := make([]SettingType, 0, len(prec)+len(missing))
+	for _, t := range prec {
+		p = append(p, t)
+		if t == ConfFileVar {
+			p = append(p, missing...)
+		}
+	}
+	return p
+}
+
+// hasType returns if types has typ.
+func hasType(types []SettingType, typ SettingType) bool {
+	for _, t := range types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// isSourceType returns if typ is the SettingType of a source that has been
+// added to settings. This assumes the lock has been obtained.
+func (s *Settings) isSourceType(typ SettingType) bool {
+	return typ >= firstSourceType && typ < firstSourceType+SettingType(len(s.sources))
+}
+
+// rank returns the rank of the source typ in settings' order of precedence: a
+// higher rank has higher precedence. A typ that isn't a source, e.g. Basic,
+// has a rank of -1. This assumes the lock has been obtained.
+func (s *Settings) rank(typ SettingType) int {
+	for i, t := range s.sourcePrecedence() {
+		if t == typ {
+			return i
+		}
+	}
+	return -1
+}
+
+// sourceSet returns if settings has been set from the source typ. This
+// assumes the lock has been obtained.
+func (s *Settings) sourceSet(typ SettingType) bool {
+	switch typ {
+	case ConfFileVar:
+		return s.confFileVarsSet
+	case EnvVar:
+		return s.envVarsSet
+	case Flag:
+		return s.flagsParsed
+	}
+	if s.isSourceType(typ) {
+		return s.sourcesSet
+	}
+	return false
+}
+
+// sourceName returns how the source typ is referred to in errors. This
+// assumes the lock has been obtained.
+func (s *Settings) sourceName(typ SettingType) string {
+	switch typ {
+	case ConfFileVar:
+		return "the configuration file"
+	case EnvVar:
+		return "env vars"
+	case Flag:
+		return "flags"
+	}
+	if s.isSourceType(typ) {
+		return "source " + s.sources[typ-firstSourceType].Name()
+	}
+	return typ.String()
+}
+
+// overridden returns if setting v was set by a source that has higher
+// precedence than typ. This assumes the lock has been obtained.
+func (s *Settings) overridden(v setting, typ SettingType) bool {
+	return s.rank(v.SetBy) > s.rank(typ)
+}
+
+// Precedence returns the order of precedence of the standard settings'
+// sources, from lowest to highest.
+func Precedence() []SettingType { return std.Precedence() }
+
+// SetPrecedence sets the order of precedence of the standard settings'
+// sources, from lowest to highest. See Settings.SetPrecedence.
+func SetPrecedence(types ...SettingType) error { return std.SetPrecedence(types...) }
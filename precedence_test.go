@@ -0,0 +1,148 @@
+package contour
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSetPrecedence(t *testing.T) {
+	tests := []struct {
+		types []SettingType
+		err   string
+	}{
+		{[]SettingType{EnvVar, ConfFileVar, Flag}, ""},
+		{[]SettingType{Flag, ConfFileVar, EnvVar}, ""},
+		{[]SettingType{ConfFileVar, EnvVar}, "[configuration file var env var]: invalid precedence: must have each of configuration file var, env var, and flag once"},
+		{[]SettingType{ConfFileVar, EnvVar, EnvVar}, "[configuration file var env var env var]: invalid precedence: must have each of configuration file var, env var, and flag once"},
+		{[]SettingType{ConfFileVar, EnvVar, Basic}, "[configuration file var env var basic]: invalid precedence: must have each of configuration file var, env var, and flag once"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		err := tst.SetPrecedence(test.types...)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%v: got %q; want %q", test.types, err, test.err)
+			}
+			if p := tst.Precedence(); !reflect.DeepEqual(p, defaultPrecedence) {
+				t.Errorf("%v: got %v; want %v", test.types, p, defaultPrecedence)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%v: got no error; want %q", test.types, test.err)
+			continue
+		}
+		if p := tst.Precedence(); !reflect.DeepEqual(p, test.types) {
+			t.Errorf("got %v; want %v", p, test.types)
+		}
+	}
+
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	table, _ := tst.AddSource(mapSource{name: "table"})
+	service, _ := tst.AddSource(mapSource{name: "service"})
+	// the added sources are right after the configuration file by default
+	if p, want := tst.Precedence(), []SettingType{ConfFileVar, table, service, EnvVar, Flag}; !reflect.DeepEqual(p, want) {
+		t.Errorf("got %v; want %v", p, want)
+	}
+	srcTests := []struct {
+		types []SettingType
+		prec  []SettingType
+		err   string
+	}{
+		{[]SettingType{ConfFileVar, EnvVar, service, Flag}, []SettingType{ConfFileVar, table, EnvVar, service, Flag}, ""},
+		{[]SettingType{service, ConfFileVar, EnvVar, Flag, table}, []SettingType{service, ConfFileVar, EnvVar, Flag, table}, ""},
+		{[]SettingType{ConfFileVar, EnvVar, table, table, Flag}, nil, "[configuration file var env var source source flag]: invalid precedence: must have each of configuration file var, env var, and flag once"},
+		{[]SettingType{ConfFileVar, EnvVar, service + 1, Flag}, nil, "[configuration file var env var source flag]: invalid precedence: must have each of configuration file var, env var, and flag once"},
+	}
+	for _, test := range srcTests {
+		err := tst.SetPrecedence(test.types...)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%v: got %q; want %q", test.types, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%v: got no error; want %q", test.types, test.err)
+			continue
+		}
+		if p := tst.Precedence(); !reflect.DeepEqual(p, test.prec) {
+			t.Errorf("%v: got %v; want %v", test.types, p, test.prec)
+		}
+	}
+
+	tst = New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.RegisterIntEnvVar("port", 8080)
+	tst.Set()
+	if err := tst.SetPrecedence(EnvVar, ConfFileVar, Flag); err != ErrSourcesSet {
+		t.Errorf("got %v; want %v", err, ErrSourcesSet)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "app.json")
+	err = ioutil.WriteFile(fname, []byte(`{"host": "conf.example.com"}`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	os.Setenv("APP_HOST", "env.example.com")
+	defer os.Unsetenv("APP_HOST")
+	tests := []struct {
+		types      []SettingType
+		args       []string
+		flagsFirst bool
+		host       string
+		origin     string
+	}{
+		{nil, nil, false, "env.example.com", "env var APP_HOST"},
+		{nil, []string{"-host", "flag.example.com"}, false, "flag.example.com", "flag -host"},
+		{[]SettingType{EnvVar, ConfFileVar, Flag}, nil, false, "conf.example.com", "conf file " + fname},
+		{[]SettingType{EnvVar, ConfFileVar, Flag}, []string{"-host", "flag.example.com"}, false, "flag.example.com", "flag -host"},
+		{[]SettingType{ConfFileVar, Flag, EnvVar}, []string{"-host", "flag.example.com"}, false, "env.example.com", "env var APP_HOST"},
+		{[]SettingType{Flag, ConfFileVar, EnvVar}, []string{"-host", "flag.example.com"}, true, "env.example.com", "env var APP_HOST"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetConfFilename(fname)
+		tst.SetUsage(func() {})
+		if test.types != nil {
+			err = tst.SetPrecedence(test.types...)
+			if err != nil {
+				t.Errorf("%v: unexpected error: %s", test.types, err)
+				continue
+			}
+		}
+		tst.RegisterStringFlag("host", "", "localhost", "localhost", "")
+		if test.flagsFirst {
+			_, err = tst.ParseFlags(test.args)
+			if err == nil {
+				err = tst.Set()
+			}
+		} else {
+			err = tst.Set()
+			if err == nil {
+				_, err = tst.ParseFlags(test.args)
+			}
+		}
+		if err != nil {
+			t.Errorf("%v %v: unexpected error: %s", test.types, test.args, err)
+			continue
+		}
+		if v := tst.String("host"); v != test.host {
+			t.Errorf("%v %v: got %q; want %q", test.types, test.args, v, test.host)
+		}
+		if o := tst.Origin("host"); o != test.origin {
+			t.Errorf("%v %v: origin: got %q; want %q", test.types, test.args, o, test.origin)
+		}
+	}
+}
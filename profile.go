@@ -0,0 +1,327 @@
+package contour
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// The configuration file key whose value is the profiles' sections, by
+	// profile name.
+	profilesKey = "profiles"
+	// The key of a YAML document whose value is the document's profile.
+	profileKey = "profile"
+	// The usage of the built-in profile flag.
+	profileUsage = "the configuration profile to use"
+)
+
+// Profile returns settings' active profile, e.g. dev; an empty string is
+// returned if settings doesn't have one. The profile flag, see SetProfileVar,
+// takes precedence over the profile's environment variable, which takes
+// precedence over the profile set by SetProfile.
+func (s *Settings) Profile() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.activeProfile()
+}
+
+// SetProfile sets settings' profile, e.g. dev, staging, or prod. A profile
+// selects the values that override the configuration file's, in order of
+// precedence:
+//    the section of the configuration file's profiles key with the
+//    profile's name, e.g. the TOML table [profiles.dev]
+//    the YAML documents, after the first, of a YAML configuration file
+//    whose profile key is the profile's name, e.g. profile: dev
+//    the file whose name is the configuration file's name, the profile's
+//    name, and the configuration file's extension, e.g. app.dev.yaml; it's
+//    looked for like the configuration file and it's ok if it's missing
+//
+// The keys of an INI or .properties configuration file are flat, so a
+// profile's keys are prefixed with the profiles key and the profile's name,
+// e.g. the INI section [profiles.dev].
+//
+// Profiles override the configuration file's top level keys, e.g. an
+// interface{} setting's value is replaced, not merged. The origin of the
+// values in the configuration file is the file and the profile, e.g. "conf
+// file app.yaml (profile dev)". The profiles key isn't a setting, unless
+// settings has a setting with its name.
+//
+// If settings has already been set from the configuration file, an
+// ErrSourcesSet is returned.
+func (s *Settings) SetProfile(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.confFileVarsSet {
+		return ErrSourcesSet
+	}
+	s.profile = name
+	return nil
+}
+
+// ProfileVar returns the name of settings' built-in profile flag and the
+// setting name of its environment variable; an empty string is returned if
+// settings doesn't have them.
+func (s *Settings) ProfileVar() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.profileVar
+}
+
+// SetProfileVar sets the name of settings' built-in profile flag, which also
+// names the profile's environment variable, e.g. profile for --profile and
+// APP_PROFILE, if settings is named app. Either one sets the active profile,
+// see SetProfile. The environment variable is checked when the configuration
+// file is set, even if settings doesn't use environment variables. If the
+// flags are parsed after the configuration file has been set and the flag
+// selects a different profile, the profiles' values are replaced, except for
+// the values that were set by a source with higher precedence than the
+// configuration file, e.g. the flags.
+//
+// If name is empty, settings doesn't have a profile flag, which is the
+// default. If settings has a setting, short flag, or alias with the name, or
+// it's the name of the override flag, the flag isn't added. If the flags have
+// already been parsed, an ErrFlagsParsed is returned.
+func (s *Settings) SetProfileVar(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flagsParsed {
+		return ErrFlagsParsed
+	}
+	s.profileVar = name
+	if name != "" {
+		s.useFlags = true
+	}
+	return nil
+}
+
+// hasProfileFlag returns if settings has the profile flag: it has been set
+// and its name isn't used by anything else. This assumes the lock has been
+// obtained.
+func (s *Settings) hasProfileFlag() bool {
+	if s.profileVar == "" || s.profileVar == completionFlag && s.completionFlag {
+		return false
+	}
+	if s.profileVar == s.overrideFlag {
+		return false
+	}
+	if _, ok := s.settings[s.profileVar]; ok {
+		return false
+	}
+	if _, ok := s.shortFlags[s.profileVar]; ok {
+		return false
+	}
+	_, ok := s.aliases[s.profileVar]
+	return !ok
+}
+
+// setProfileFlag adds the profile flag to the flagSet, if settings has one.
+// This assumes the lock has been obtained.
+func (s *Settings) setProfileFlag() {
+	if !s.hasProfileFlag() {
+		return
+	}
+	s.flagSet.String(s.profileVar, "", profileUsage)
+}
+
+// activeProfile returns settings' active profile. This assumes the lock has
+// been obtained.
+func (s *Settings) activeProfile() string {
+	if s.flagProfile != "" {
+		return s.flagProfile
+	}
+	if s.profileVar != "" {
+		if v := s.getenv(s.EnvVarName(s.profileVar)); v != "" {
+			return v
+		}
+	}
+	return s.profile
+}
+
+// profileValues returns the values of the profile from the configuration b,
+// in the format f, whose map is m, and, if fname isn't empty, from the
+// profile's file, which is named after the configuration file fname. The name
+// is used in errors and origin, with the profile, is the values' origin. This
+// assumes the lock has been obtained.
+func (s *Settings) profileValues(profile, name, origin string, f Format, m map[string]interface{}, b []byte, fname string) (map[string]SourceValue, error) {
+	origin += " (profile " + profile + ")"
+	vals := map[string]SourceValue{}
+	overlay := func(origin string, m map[string]interface{}) error {
+		v, err := s.confMapValues(origin, f, m)
+		if err != nil {
+			return err
+		}
+		for k, val := range v {
+			vals[k] = val
+		}
+		return nil
+	}
+	if _, ok := s.settings[profilesKey]; !ok {
+		profiles, _ := m[profilesKey].(map[string]interface{})
+		if p, ok := profiles[profile].(map[string]interface{}); ok {
+			err := overlay(origin, p)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if f == YAML {
+		docs, err := yamlProfileDocs(b, profile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		for _, d := range docs {
+			err = overlay(origin, d)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if fname == "" {
+		return vals, nil
+	}
+	ext := filepath.Ext(fname)
+	pname := strings.TrimSuffix(fname, ext) + "." + profile + ext
+	pb, err := s.readConfFile(pname)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return vals, nil
+		}
+		return nil, err
+	}
+	pm, err := confMap(pname, f, pb)
+	if err != nil || pm == nil {
+		return vals, err
+	}
+	err = overlay("conf file "+pname, pm)
+	if err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// yamlProfileDocs returns the YAML documents in b, after the first, whose
+// profile key is the profile, without the profile key.
+func yamlProfileDocs(b []byte, profile string) ([]map[string]interface{}, error) {
+	var docs []map[string]interface{}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for i := 0; ; i++ {
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		m, ok := stringMaps(doc).(map[string]interface{})
+		if i == 0 || !ok || fmt.Sprintf("%v", m[profileKey]) != profile {
+			continue
+		}
+		delete(m, profileKey)
+		docs = append(docs, m)
+	}
+}
+
+// nestProfiles moves the dotted keys of m, a configuration whose keys are
+// flat, that are in the profiles key, e.g. profiles.dev.host, to the profiles'
+// sections, e.g. the host key of the dev section of the profiles key, like
+// they would be in a configuration that nests its keys.
+func nestProfiles(m map[string]interface{}) {
+	for k, v := range m {
+		parts := strings.SplitN(k, ".", 3)
+		if len(parts) != 3 || parts[0] != profilesKey {
+			continue
+		}
+		profiles, ok := m[profilesKey].(map[string]interface{})
+		if !ok {
+			profiles = map[string]interface{}{}
+			m[profilesKey] = profiles
+		}
+		p, ok := profiles[parts[1]].(map[string]interface{})
+		if !ok {
+			p = map[string]interface{}{}
+			profiles[parts[1]] = p
+		}
+		p[parts[2]] = v
+		delete(m, k)
+	}
+}
+
+// applyProfileFlag sets the active profile to the profile flag's value, if it
+// was used. If the configuration file, or the reader that replaced it, has
+// already been set with a different profile, its values are set again with
+// the flag's profile. This assumes the lock has been obtained and that the
+// flags have been parsed.
+func (s *Settings) applyProfileFlag() error {
+	if !s.hasProfileFlag() {
+		return nil
+	}
+	f := s.flagSet.Lookup(s.profileVar)
+	if f.Value.String() == "" {
+		return nil
+	}
+	s.flagProfile = f.Value.String()
+	if !s.confFileVarsSet || s.confSrc == nil || s.confProfile == s.flagProfile {
+		return nil
+	}
+	vals, err := s.confSrc.Load()
+	if err != nil {
+		return err
+	}
+	s.confProfile = s.flagProfile
+	// the values that the previous profile set, that this one doesn't,
+	// are set back to what they were before the configuration.
+	for k, prev := range s.confPrev {
+		if _, ok := vals[k]; ok || !s.fromConfFile(k) {
+			continue
+		}
+		v := s.settings[k]
+		v.Value, v.Origin, v.SetBy = prev.Value, prev.Origin, prev.SetBy
+		s.settings[k] = v
+	}
+	for k, val := range vals {
+		v, ok := s.settings[k]
+		if !ok {
+			return fmt.Errorf("%s: %s", s.confSrc.Name(), SettingNotFoundError{k: k})
+		}
+		if !v.IsConfFileVar {
+			return fmt.Errorf("%s: %s", s.confSrc.Name(), updateError{typ: ConfFileVar, k: k, slug: fmt.Sprintf("is not a %s", ConfFileVar)})
+		}
+		if _, ok := s.confPrev[k]; !ok {
+			s.confPrev[k] = v
+		}
+		if !s.fromConfFile(k) {
+			continue
+		}
+		v.Value, v.Origin, v.SetBy = val.Value, val.Origin, ConfFileVar
+		s.settings[k] = v
+	}
+	return nil
+}
+
+// fromConfFile returns if setting k's value can be replaced by the
+// configuration file's, or the reader's that replaced it: it wasn't set by
+// anything with higher precedence than it. This assumes the lock has been
+// obtained.
+func (s *Settings) fromConfFile(k string) bool {
+	return !s.overridden(s.settings[k], ConfFileVar)
+}
+
+// Profile returns the standard settings' active profile.
+func Profile() string { return std.Profile() }
+
+// SetProfile sets the standard settings' profile. See Settings.SetProfile.
+func SetProfile(name string) error { return std.SetProfile(name) }
+
+// ProfileVar returns the name of the standard settings' built-in profile flag.
+func ProfileVar() string { return std.ProfileVar() }
+
+// SetProfileVar sets the name of the standard settings' built-in profile flag
+// and environment variable. See Settings.SetProfileVar.
+func SetProfileVar(name string) error { return std.SetProfileVar(name) }
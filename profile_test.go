@@ -0,0 +1,215 @@
+package contour
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	files := map[string]string{
+		"app.toml": `host = "base.example.com"
+port = 8080
+
+[profiles.dev]
+host = "dev.example.com"
+debug = true
+`,
+		"app.yaml": `host: base.example.com
+port: 8080
+---
+profile: staging
+host: staging.example.com
+---
+profile: dev
+host: dev.example.com
+`,
+		"app.json":      `{"host": "base.example.com", "port": 8080}`,
+		"app.prod.json": `{"host": "prod.example.com", "port": 443}`,
+	}
+	for name, v := range files {
+		err = ioutil.WriteFile(filepath.Join(tmpDir, name), []byte(v), 0644)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	tests := []struct {
+		fname   string
+		profile string
+		host    string
+		port    int
+		debug   bool
+		origin  string
+	}{
+		{"app.toml", "", "base.example.com", 8080, false, "conf file app.toml"},
+		{"app.toml", "dev", "dev.example.com", 8080, true, "conf file app.toml (profile dev)"},
+		{"app.toml", "prod", "base.example.com", 8080, false, "conf file app.toml"},
+		{"app.yaml", "staging", "staging.example.com", 8080, false, "conf file app.yaml (profile staging)"},
+		{"app.yaml", "dev", "dev.example.com", 8080, false, "conf file app.yaml (profile dev)"},
+		{"app.json", "prod", "prod.example.com", 443, false, "conf file app.prod.json"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetConfFilename(test.fname)
+		tst.SetConfFilePaths([]string{tmpDir})
+		tst.SetProfile(test.profile)
+		tst.RegisterStringConfFileVar("host", "localhost")
+		tst.RegisterIntConfFileVar("port", 80)
+		tst.RegisterBoolConfFileVar("debug", false)
+		err = tst.Set()
+		if err != nil {
+			t.Errorf("%s %s: unexpected error: %s", test.fname, test.profile, err)
+			continue
+		}
+		if v := tst.String("host"); v != test.host {
+			t.Errorf("%s %s: host: got %q; want %q", test.fname, test.profile, v, test.host)
+		}
+		if v := tst.Int("port"); v != test.port {
+			t.Errorf("%s %s: port: got %d; want %d", test.fname, test.profile, v, test.port)
+		}
+		if v := tst.Bool("debug"); v != test.debug {
+			t.Errorf("%s %s: debug: got %v; want %v", test.fname, test.profile, v, test.debug)
+		}
+		if o := tst.Origin("host"); o != test.origin {
+			t.Errorf("%s %s: origin: got %q; want %q", test.fname, test.profile, o, test.origin)
+		}
+		if err = tst.SetProfile("other"); err != ErrSourcesSet {
+			t.Errorf("%s %s: got %v; want %v", test.fname, test.profile, err, ErrSourcesSet)
+		}
+	}
+}
+
+func TestProfileVar(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "app.toml")
+	err = ioutil.WriteFile(fname, []byte(`host = "base.example.com"
+
+[profiles.dev]
+host = "dev.example.com"
+debug = true
+
+[profiles.prod]
+host = "prod.example.com"
+port = 443
+`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	os.Setenv("APP_PROFILE", "dev")
+	defer os.Unsetenv("APP_PROFILE")
+	os.Setenv("APP_PORT", "9090")
+	defer os.Unsetenv("APP_PORT")
+	tests := []struct {
+		args    []string
+		profile string
+		host    string
+		port    int
+		debug   bool
+	}{
+		{nil, "dev", "dev.example.com", 9090, true},
+		{[]string{"--profile", "prod"}, "prod", "prod.example.com", 9090, false},
+		{[]string{"--profile=prod", "--host", "flag.example.com"}, "prod", "flag.example.com", 9090, false},
+		{[]string{"--profile", "dev"}, "dev", "dev.example.com", 9090, true},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetConfFilename(fname)
+		tst.SetPOSIXFlags(true)
+		tst.SetUsage(func() {})
+		tst.SetProfile("staging")
+		tst.SetProfileVar("profile")
+		tst.RegisterStringFlag("host", "", "localhost", "localhost", "")
+		tst.RegisterIntFlag("port", "", 80, "80", "")
+		tst.RegisterBoolConfFileVar("debug", false)
+		err = tst.Set()
+		if err != nil {
+			t.Errorf("%v: unexpected error: %s", test.args, err)
+			continue
+		}
+		if p := tst.Profile(); p != "dev" {
+			t.Errorf("%v: got %q; want dev", test.args, p)
+		}
+		_, err = tst.ParseFlags(test.args)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %s", test.args, err)
+			continue
+		}
+		if p := tst.Profile(); p != test.profile {
+			t.Errorf("%v: got %q; want %q", test.args, p, test.profile)
+		}
+		if v := tst.String("host"); v != test.host {
+			t.Errorf("%v: host: got %q; want %q", test.args, v, test.host)
+		}
+		if v := tst.Int("port"); v != test.port {
+			t.Errorf("%v: port: got %d; want %d", test.args, v, test.port)
+		}
+		if v := tst.Bool("debug"); v != test.debug {
+			t.Errorf("%v: debug: got %v; want %v", test.args, v, test.debug)
+		}
+	}
+}
+
+func TestProfileReader(t *testing.T) {
+	conf := `host: base.example.com
+profiles:
+  dev:
+    host: dev.example.com
+    debug: true
+  prod:
+    host: prod.example.com
+`
+	tests := []struct {
+		profile string
+		args    []string
+		host    string
+		debug   bool
+		origin  string
+	}{
+		{"", nil, "base.example.com", false, "conf reader"},
+		{"dev", nil, "dev.example.com", true, "conf reader (profile dev)"},
+		{"", []string{"--profile", "prod"}, "prod.example.com", false, "conf reader (profile prod)"},
+		{"dev", []string{"--profile", "prod"}, "prod.example.com", false, "conf reader (profile prod)"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		// the configuration file doesn't exist: the profile flag has to
+		// reload the reader
+		tst.SetConfFilename(filepath.Join(os.TempDir(), "contour-missing", "app.yaml"))
+		tst.SetPOSIXFlags(true)
+		tst.SetUsage(func() {})
+		tst.SetProfile(test.profile)
+		tst.SetProfileVar("profile")
+		tst.RegisterStringConfFileVar("host", "localhost")
+		tst.RegisterBoolConfFileVar("debug", false)
+		err := tst.SetFromReader(strings.NewReader(conf), YAML)
+		if err != nil {
+			t.Errorf("%q %v: unexpected error: %s", test.profile, test.args, err)
+			continue
+		}
+		_, err = tst.ParseFlags(test.args)
+		if err != nil {
+			t.Errorf("%q %v: unexpected error: %s", test.profile, test.args, err)
+			continue
+		}
+		if v := tst.String("host"); v != test.host {
+			t.Errorf("%q %v: host: got %q; want %q", test.profile, test.args, v, test.host)
+		}
+		if v := tst.Bool("debug"); v != test.debug {
+			t.Errorf("%q %v: debug: got %v; want %v", test.profile, test.args, v, test.debug)
+		}
+		if o := tst.Origin("host"); o != test.origin {
+			t.Errorf("%q %v: origin: got %q; want %q", test.profile, test.args, o, test.origin)
+		}
+	}
+}
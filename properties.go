@@ -0,0 +1,49 @@
+package contour
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/magiconair/properties"
+)
+
+// unmarshalProperties returns the Java .properties configuration b as a map of
+// its keys. All of the values are strings; ${key} references aren't
+// expanded.
+func unmarshalProperties(b []byte) (map[string]interface{}, error) {
+	l := &properties.Loader{Encoding: properties.UTF8, DisableExpansion: true}
+	p, err := l.LoadBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	for k, v := range p.Map() {
+		m[k] = v
+	}
+	nestProfiles(m)
+	return m, nil
+}
+
+// marshalProperties returns m, a map of string values by key, as a Java
+// .properties file, with the keys sorted.
+func marshalProperties(m map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	p := properties.NewProperties()
+	p.DisableExpansion = true
+	for _, k := range keys {
+		_, _, err := p.Set(k, m[k])
+		if err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	_, err := p.Write(&buf, properties.UTF8)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
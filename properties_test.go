@@ -0,0 +1,52 @@
+package contour
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProperties(t *testing.T) {
+	tests := []struct {
+		conf   string
+		host   string
+		port   int
+		labels map[string]interface{}
+		err    string
+	}{
+		{"db.host=db.example.com\ndb.port=5432\n", "db.example.com", 5432, nil, ""},
+		{"# comment\ndb.host : ${HOST}\nlabels = {\"env\": \"prod\"}\n", "${HOST}", 0, map[string]interface{}{"env": "prod"}, ""},
+		{"db.port=many\n", "", 0, nil, `configuration file var db.port: cannot parse "many" as int`},
+		{"db.user=app\n", "", 0, nil, "conf reader: db.user: setting not found"},
+	}
+	for i, test := range tests {
+		tst := New("app")
+		tst.RegisterStringConfFileVar("db.host", "")
+		tst.RegisterIntConfFileVar("db.port", 0)
+		tst.RegisterInterfaceConfFileVar("labels", nil)
+		err := tst.SetFromReader(strings.NewReader(test.conf), Properties)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%d: got %q; want %q", i, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%d: got no error; want %q", i, test.err)
+			continue
+		}
+		if v := tst.String("db.host"); v != test.host {
+			t.Errorf("%d: db.host: got %q; want %q", i, v, test.host)
+		}
+		if v := tst.Int("db.port"); v != test.port {
+			t.Errorf("%d: db.port: got %d; want %d", i, v, test.port)
+		}
+		if test.labels == nil {
+			continue
+		}
+		v, _ := tst.InterfaceE("labels")
+		m, _ := v.(map[string]interface{})
+		if m["env"] != test.labels["env"] {
+			t.Errorf("%d: labels: got %v; want %v", i, v, test.labels)
+		}
+	}
+}
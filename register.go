@@ -33,7 +33,13 @@ import (
 // usage of Add functions should be preferred. These setting will not be
 // exposed to the configuration file, as an environment variable, or as a flag.
 //
-// For non string, bool, int, and int64 types, the type must be "interface{}"
+// For non string, bool, int, and int64 types, the type must be "interface{}".
+//
+// If IsFlag is true, the value must be of the data type, typ. An interface{}
+// flag's value is either a flag.Value, which is used as the flag's value and
+// sets itself, or a value that can be encoded as JSON; in which case the
+// flag's value is parsed as JSON, or YAML, like an interface{} environment
+// variable. Otherwise an error is returned.
 func (s *Settings) RegisterSetting(typ, name, short string, value interface{}, dflt, usage string, IsCore, IsConfFileVar, IsEnvVar, IsFlag bool) error {
 	dType := parseDataType(typ)
 	s.mu.Lock()
@@ -50,6 +56,16 @@ func (s *Settings) registerSetting(sTyp SettingType, typ dataType, name, short s
 		// Settings can't be re-registered.
 		return SettingExistsError{typ: sTyp, k: name}
 	}
+	if n, ok := s.aliases[name]; ok {
+		return AliasExistsError{k: name, alias: name, aliasName: n}
+	}
+
+	if IsFlag {
+		err := checkFlagValue(typ, name, value)
+		if err != nil {
+			return err
+		}
+	}
 
 	// mapping shortcodes make lookup easier
 	if short != "" && IsFlag {
@@ -57,6 +73,9 @@ func (s *Settings) registerSetting(sTyp SettingType, typ dataType, name, short s
 		if ok {
 			return ShortFlagExistsError{k: name, short: short, shortName: v}
 		}
+		if v, ok := s.aliases[short]; ok {
+			return AliasExistsError{k: name, alias: short, aliasName: v}
+		}
 		s.shortFlags[short] = name
 	}
 
@@ -72,6 +91,7 @@ func (s *Settings) registerSetting(sTyp SettingType, typ dataType, name, short s
 		IsConfFileVar: IsConfFileVar,
 		IsEnvVar:      IsEnvVar,
 		IsFlag:        IsFlag,
+		Origin:        "default",
 	}
 	// if it's a conf file setting, add it to the confFileVars map
 	if IsConfFileVar {
@@ -138,10 +158,11 @@ func (s *Settings) registerInt64ConfFileVar(k string, v int64) error {
 	return s.registerConfFileVar(_int64, k, v, strconv.FormatInt(v, 10))
 }
 
-// RegisterInterfaceConfFileVar registers an int setting using k for its key
-// and v for its value. Once registered, the value of this setting can only be
-// updated from a configuration file. If k already exists a SettingExistsError
-// will be returned. If k is empty, an ErrNoSettingName will be returned.
+// RegisterInterfaceConfFileVar registers an interface{} setting using k for
+// its key and v for its value. Once registered, the value of this setting can
+// only be updated from a configuration file. If k already exists a
+// SettingExistsError will be returned. If k is empty, an ErrNoSettingName
+// will be returned.
 func (s *Settings) RegisterInterfaceConfFileVar(k string, v interface{}) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -151,7 +172,7 @@ func (s *Settings) RegisterInterfaceConfFileVar(k string, v interface{}) error {
 // assumes the lock has been obtained. Unexported register methods always
 // return an error.
 func (s *Settings) registerInterfaceConfFileVar(k string, v interface{}) error {
-	return s.registerConfFileVar(_int, k, v, fmt.Sprintf("%v", v))
+	return s.registerConfFileVar(_interface, k, v, fmt.Sprintf("%v", v))
 }
 
 // RegisterStringConfFileVar registers a string setting using k for its key and
@@ -175,150 +196,176 @@ func (s *Settings) registerConfFileVar(typ dataType, k string, v interface{}, df
 	return s.registerSetting(ConfFileVar, typ, k, "", v, dflt, "", false, true, false, false)
 }
 
-// RegisterBoolEnvVar registers a bool setting using k for its key and v for
-// its value. Once registered, the value of this setting can only be updated
-// from a configuration file or an environment variable. If k already exists a
+// RegisterBoolEnvVar registers a bool setting using k for its key and v for its
+// value. Once registered, the value of this setting can only be updated from a
+// configuration file or an environment variable. If k already exists a
 // SettingExistsError will be returned. If k is empty, an ErrNoSettingName will
-// be returned.
-func (s *Settings) RegisterBoolEnvVar(k string, v bool) error {
+// be returned. The setting can be hidden or deprecated with opts, see Hide and
+// Deprecate.
+func (s *Settings) RegisterBoolEnvVar(k string, v bool, opts ...RegisterOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.registerBoolEnvVar(k, v)
+	return s.registerBoolEnvVar(k, v, opts...)
 }
 
 // assumes the lock has been obtained. Unexported register methods always
 // return an error.
-func (s *Settings) registerBoolEnvVar(k string, v bool) error {
-	return s.registerEnvVar(_bool, k, v, strconv.FormatBool(v))
+func (s *Settings) registerBoolEnvVar(k string, v bool, opts ...RegisterOption) error {
+	return s.registerEnvVar(_bool, k, v, strconv.FormatBool(v), opts...)
 }
 
 // RegisterIntEnvVar registers an int setting using k for its key and v for its
 // value. Once registered, the value of this setting can only be updated from a
 // configuration file or an environment variable. If k already exists a
 // SettingExistsError will be returned. If k is empty, an ErrNoSettingName will
-// be returned.
-func (s *Settings) RegisterIntEnvVar(k string, v int) error {
+// be returned. The setting can be hidden or deprecated with opts, see Hide and
+// Deprecate.
+func (s *Settings) RegisterIntEnvVar(k string, v int, opts ...RegisterOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.registerIntEnvVar(k, v)
+	return s.registerIntEnvVar(k, v, opts...)
 }
 
 // assumes the lock has been obtained.
-func (s *Settings) registerIntEnvVar(k string, v int) error {
-	return s.registerEnvVar(_int, k, v, strconv.Itoa(v))
+func (s *Settings) registerIntEnvVar(k string, v int, opts ...RegisterOption) error {
+	return s.registerEnvVar(_int, k, v, strconv.Itoa(v), opts...)
 }
 
 // RegisterInt64EnvVar registers an int64 setting using k for its key and v for
 // its value. Once registered, the value of this setting can only be updated
 // from a configuration file or an environment variable. If k already exists a
 // SettingExistsError will be returned. If k is empty, an ErrNoSettingName will
-// be returned.
-func (s *Settings) RegisterInt64EnvVar(k string, v int64) error {
+// be returned. The setting can be hidden or deprecated with opts, see Hide and
+// Deprecate.
+func (s *Settings) RegisterInt64EnvVar(k string, v int64, opts ...RegisterOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.registerInt64EnvVar(k, v)
+	return s.registerInt64EnvVar(k, v, opts...)
 }
 
 // assumes the lock has been obtained.
-func (s *Settings) registerInt64EnvVar(k string, v int64) error {
-	return s.registerEnvVar(_int64, k, v, strconv.FormatInt(v, 10))
+func (s *Settings) registerInt64EnvVar(k string, v int64, opts ...RegisterOption) error {
+	return s.registerEnvVar(_int64, k, v, strconv.FormatInt(v, 10), opts...)
 }
 
-// RegisterStringEnvVar registers a string setting using k for its key and v
-// for its value. Once registered, the value of this setting can only be
-// updated from a configuration file or an environment variable. If k already
-// exists a SettingExistsError will be returned. If k is empty, an
-// ErrNoSettingName will be returned.
-func (s *Settings) RegisterStringEnvVar(k, v string) error {
+// RegisterStringEnvVar registers a string setting using k for its key and v for
+// its value. Once registered, the value of this setting can only be updated
+// from a configuration file or an environment variable. If k already exists a
+// SettingExistsError will be returned. If k is empty, an ErrNoSettingName will
+// be returned. The setting can be hidden or deprecated with opts, see Hide and
+// Deprecate.
+func (s *Settings) RegisterStringEnvVar(k, v string, opts ...RegisterOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.registerStringEnvVar(k, v)
+	return s.registerStringEnvVar(k, v, opts...)
 }
 
 // assumes the lock has been obtained.
-func (s *Settings) registerStringEnvVar(k, v string) error {
-	return s.registerEnvVar(_string, k, v, v)
+func (s *Settings) registerStringEnvVar(k, v string, opts ...RegisterOption) error {
+	return s.registerEnvVar(_string, k, v, v, opts...)
 }
 
-func (s *Settings) registerEnvVar(typ dataType, k string, v interface{}, dflt string) error {
+func (s *Settings) registerEnvVar(typ dataType, k string, v interface{}, dflt string, opts ...RegisterOption) error {
+	o, err := s.registerOptions(typ, k, opts)
+	if err != nil {
+		return err
+	}
 	s.useConfFile = true // registering a conf file var means use a conf file unless explicitly set not to
 	s.useEnvVars = true  // registering an env var means use env vars unless explictly set not to
-	return s.registerSetting(EnvVar, typ, k, "", v, dflt, "", false, true, true, false)
+	err = s.registerSetting(EnvVar, typ, k, "", v, dflt, "", false, true, true, false)
+	if err != nil {
+		return err
+	}
+	s.setRegisterOptions(k, o)
+	return nil
 }
 
 // RegisterBoolFlag registers a bool setting using k for its key and v for its
 // value. Once registered, the value of this setting can be updated from a
-// configuration file, an environment variable, or a flag. If k already exists
-// a SettingExistsError will be returned. If k is empty, an ErrNoSettingName
-// will be returned.
-func (s *Settings) RegisterBoolFlag(k, short string, v bool, dflt, usage string) error {
+// configuration file, an environment variable, or a flag. If k already exists a
+// SettingExistsError will be returned. If k is empty, an ErrNoSettingName will
+// be returned. The setting can be hidden or deprecated with opts, see Hide and
+// Deprecate.
+func (s *Settings) RegisterBoolFlag(k, short string, v bool, dflt, usage string, opts ...RegisterOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.registerBoolFlag(k, short, v, dflt, usage)
+	return s.registerBoolFlag(k, short, v, dflt, usage, opts...)
 }
 
 // assumes the lock has been obtained. Unexported register methods always
 // return an error.
-func (s *Settings) registerBoolFlag(k, short string, v bool, dflt, usage string) error {
-	return s.registerFlag(_bool, k, short, v, dflt, usage)
+func (s *Settings) registerBoolFlag(k, short string, v bool, dflt, usage string, opts ...RegisterOption) error {
+	return s.registerFlag(_bool, k, short, v, dflt, usage, opts...)
 }
 
 // RegisterIntFlag registers an int setting using k for its key and v for its
 // value. Once registered, the value of this setting can be updated from a
-// configuration file, an environment variable, or a flag. If k already exists
-// a SettingExistsError will be returned. If k is empty, an ErrNoSettingName
-// will be returned
-func (s *Settings) RegisterIntFlag(k, short string, v int, dflt, usage string) error {
+// configuration file, an environment variable, or a flag. If k already exists a
+// SettingExistsError will be returned. If k is empty, an ErrNoSettingName will
+// be returned. The setting can be hidden or deprecated with opts, see Hide and
+// Deprecate.
+func (s *Settings) RegisterIntFlag(k, short string, v int, dflt, usage string, opts ...RegisterOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.registerIntFlag(k, short, v, dflt, usage)
+	return s.registerIntFlag(k, short, v, dflt, usage, opts...)
 }
 
 // assumes the lock has been obtained. Unexported register methods always
 // return an error.
-func (s *Settings) registerIntFlag(k, short string, v int, dflt, usage string) error {
-	return s.registerFlag(_int, k, short, v, dflt, usage)
+func (s *Settings) registerIntFlag(k, short string, v int, dflt, usage string, opts ...RegisterOption) error {
+	return s.registerFlag(_int, k, short, v, dflt, usage, opts...)
 }
 
 // RegisterInt64Flag registers an int64 setting using k for its key and v for
 // its value. Once registered, the value of this setting can be updated from a
-// configuration file, an environment variable, or a flag. If k already exists
-// a SettingExistsError will be returned. If k is empty, an ErrNoSettingName
-// will be returned
-func (s *Settings) RegisterInt64Flag(k, short string, v int64, dflt, usage string) error {
+// configuration file, an environment variable, or a flag. If k already exists a
+// SettingExistsError will be returned. If k is empty, an ErrNoSettingName will
+// be returned. The setting can be hidden or deprecated with opts, see Hide and
+// Deprecate.
+func (s *Settings) RegisterInt64Flag(k, short string, v int64, dflt, usage string, opts ...RegisterOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.registerInt64Flag(k, short, v, dflt, usage)
+	return s.registerInt64Flag(k, short, v, dflt, usage, opts...)
 }
 
 // assumes the lock has been obtained. Unexported register methods always
 // return an error.
-func (s *Settings) registerInt64Flag(k, short string, v int64, dflt, usage string) error {
-	return s.registerFlag(_int64, k, short, v, dflt, usage)
+func (s *Settings) registerInt64Flag(k, short string, v int64, dflt, usage string, opts ...RegisterOption) error {
+	return s.registerFlag(_int64, k, short, v, dflt, usage, opts...)
 }
 
 // RegisterStringFlag registers a string setting using k for its key and v for
 // its value. Once registered, the value of this setting can be updated from a
-// configuration file, an environment variable, or a flag. If k already exists
-// a SettingExistsError will be returned. If k is empty, an ErrNoSettingName
-// will be returned
-func (s *Settings) RegisterStringFlag(k, short, v, dflt, usage string) error {
+// configuration file, an environment variable, or a flag. If k already exists a
+// SettingExistsError will be returned. If k is empty, an ErrNoSettingName will
+// be returned. The setting can be hidden or deprecated with opts, see Hide and
+// Deprecate.
+func (s *Settings) RegisterStringFlag(k, short, v, dflt, usage string, opts ...RegisterOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.registerStringFlag(k, short, v, dflt, usage)
+	return s.registerStringFlag(k, short, v, dflt, usage, opts...)
 }
 
 // assumes the lock has been obtained. Unexported register methods always
 // return an error.
-func (s *Settings) registerStringFlag(k, short, v, dflt, usage string) error {
-	return s.registerFlag(_string, k, short, v, dflt, usage)
+func (s *Settings) registerStringFlag(k, short, v, dflt, usage string, opts ...RegisterOption) error {
+	return s.registerFlag(_string, k, short, v, dflt, usage, opts...)
 }
 
-func (s *Settings) registerFlag(typ dataType, k, short string, v interface{}, dflt, usage string) error {
+func (s *Settings) registerFlag(typ dataType, k, short string, v interface{}, dflt, usage string, opts ...RegisterOption) error {
+	o, err := s.registerOptions(typ, k, opts)
+	if err != nil {
+		return err
+	}
 	s.useConfFile = true // registering a conf file var means use a conf file unless explicitly set not to
 	s.useEnvVars = true  // registering an env var means use env vars unless explictly set not to
 	s.useFlags = true    // registering a flag means use flags unless explictly set not to
-	return s.registerSetting(Flag, typ, k, short, v, dflt, usage, false, true, true, true)
+	err = s.registerSetting(Flag, typ, k, short, v, dflt, usage, false, true, true, true)
+	if err != nil {
+		return err
+	}
+	s.setRegisterOptions(k, o)
+	return nil
 }
 
 // RegisterSetting registers a setting with the standard settings. For most
@@ -375,10 +422,10 @@ func RegisterIntConfFileVar(k string, v int) error { return std.RegisterIntConfF
 // ErrNoSettingName will be returned.
 func RegisterInt64ConfFileVar(k string, v int64) error { return std.RegisterInt64ConfFileVar(k, v) }
 
-// RegisterInterfaceConfFileVar registers an int setting with the standard
-// settings using k for its key and v for its value. Once registered, the value
-// of this setting can only be updated from a configuration file. If k already
-// exists a SettingExistsError will be returned. If k is empty, an
+// RegisterInterfaceConfFileVar registers an interface{} setting with the
+// standard settings using k for its key and v for its value. Once registered,
+// the value of this setting can only be updated from a configuration file. If
+// k already exists a SettingExistsError will be returned. If k is empty, an
 // ErrNoSettingName will be returned.
 func RegisterInterfaceConfFileVar(k string, v interface{}) error {
 	return std.RegisterInterfaceConfFileVar(k, v)
@@ -396,36 +443,44 @@ func RegisterStringConfFileVar(k, v string) error { return std.RegisterStringCon
 // setting can only be updated from a configuration file or an environment
 // variable. If k already exists a SettingExistsError will be returned. If k is
 // empty, an ErrNoSettingName will be returned.
-func RegisterBoolEnvVar(k string, v bool) error { return std.RegisterBoolEnvVar(k, v) }
+func RegisterBoolEnvVar(k string, v bool, opts ...RegisterOption) error {
+	return std.RegisterBoolEnvVar(k, v, opts...)
+}
 
 // RegisterIntEnvVar registers an int setting with the standard settings using
 // k for its key and v for its value. Once registered, the value of this
 // setting can only be updated from a configuration file or an environment
 // variable. If k already exists a SettingExistsError will be returned. If k is
 // empty, an ErrNoSettingName will be returned.
-func RegisterIntEnvVar(k string, v int) error { return std.RegisterIntEnvVar(k, v) }
+func RegisterIntEnvVar(k string, v int, opts ...RegisterOption) error {
+	return std.RegisterIntEnvVar(k, v, opts...)
+}
 
 // RegisterInt64EnvVar registers an int64 setting with the standard settings
 // using k for its key and v for its value. Once registered, the value of this
 // setting can only be updated from a configuration file or an environment
 // variable. If k already exists a SettingExistsError will be returned. If k is
 // empty, an ErrNoSettingName will be returned.
-func RegisterInt64EnvVar(k string, v int64) error { return std.RegisterInt64EnvVar(k, v) }
+func RegisterInt64EnvVar(k string, v int64, opts ...RegisterOption) error {
+	return std.RegisterInt64EnvVar(k, v, opts...)
+}
 
 // RegisterStringEnvVar registers a string setting with the standard settings
 // using k for its key and v for its value. Once registered, the value of this
 // setting can only be updated from a configuration file or an environment
 // variable. If k already exists a SettingExistsError will be returned. If k is
 // empty, an ErrNoSettingName will be returned.
-func RegisterStringEnvVar(k, v string) error { return std.RegisterStringEnvVar(k, v) }
+func RegisterStringEnvVar(k, v string, opts ...RegisterOption) error {
+	return std.RegisterStringEnvVar(k, v, opts...)
+}
 
 // RegisterBoolFlag registers a bool setting with the standard settings using k
 // for its key and v for its value. Once registered, the value of this setting
 // can be updated from a configuration file, an environment variable, or a
 // flag. If k already exists a SettingExistsError will be returned. If k is
 // empty, an ErrNoSettingName will be returned.
-func RegisterBoolFlag(k, short string, v bool, dflt, usage string) error {
-	return std.RegisterBoolFlag(k, short, v, dflt, usage)
+func RegisterBoolFlag(k, short string, v bool, dflt, usage string, opts ...RegisterOption) error {
+	return std.RegisterBoolFlag(k, short, v, dflt, usage, opts...)
 }
 
 // RegisterIntFlag registers an int setting with the standard settings using k
@@ -433,8 +488,8 @@ func RegisterBoolFlag(k, short string, v bool, dflt, usage string) error {
 // can be updated from a configuration file, an environment variable, or a
 // flag. If k already exists a SettingExistsError will be returned. If k is
 // empty, an ErrNoSettingName will be returned.
-func RegisterIntFlag(k, short string, v int, dflt, usage string) error {
-	return std.RegisterIntFlag(k, short, v, dflt, usage)
+func RegisterIntFlag(k, short string, v int, dflt, usage string, opts ...RegisterOption) error {
+	return std.RegisterIntFlag(k, short, v, dflt, usage, opts...)
 }
 
 // RegisterInt64Flag registers an int64 setting with the standard settings
@@ -442,8 +497,8 @@ func RegisterIntFlag(k, short string, v int, dflt, usage string) error {
 // setting can be updated from a configuration file, an environment variable,
 // or a flag. If k already exists a SettingExistsError will be returned. If k is
 // empty, an ErrNoSettingName will be returned.
-func RegisterInt64Flag(k, short string, v int64, dflt, usage string) error {
-	return std.RegisterInt64Flag(k, short, v, dflt, usage)
+func RegisterInt64Flag(k, short string, v int64, dflt, usage string, opts ...RegisterOption) error {
+	return std.RegisterInt64Flag(k, short, v, dflt, usage, opts...)
 }
 
 // RegisterStringFlag registers a string setting with the standard settings
@@ -451,6 +506,6 @@ func RegisterInt64Flag(k, short string, v int64, dflt, usage string) error {
 // setting can be updated from a configuration file, an environment variable,
 // or a flag. If k already exists a SettingExistsError will be returned. If k is
 // empty, an ErrNoSettingName will be returned.
-func RegisterStringFlag(k, short, v, dflt, usage string) error {
-	return std.RegisterStringFlag(k, short, v, dflt, usage)
+func RegisterStringFlag(k, short, v, dflt, usage string, opts ...RegisterOption) error {
+	return std.RegisterStringFlag(k, short, v, dflt, usage, opts...)
 }
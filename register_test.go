@@ -75,6 +75,9 @@ func TestRegisterCfgSettings(t *testing.T) {
 		if IsFlag(test.name) != test.IsFlag {
 			t.Errorf("%d: expected IsFlag to be %v, got %v", i, test.IsFlag, IsFlag(test.name))
 		}
+		if typ := std.settings[test.name].Type; typ != test.typ {
+			t.Errorf("%d: type: got %s; want %s", i, typ, test.typ)
+		}
 		if !UseConfFile() {
 			t.Errorf("%d: useConfFile: got %v; want true", i, UseConfFile())
 		}
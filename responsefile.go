@@ -0,0 +1,225 @@
+package contour
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The maximum depth that response files can be nested; this also stops a
+// response file that includes itself.
+const maxResponseFileDepth = 8
+
+// UseResponseFiles returns if settings expands response files, @path, when
+// parsing flags.
+func (s *Settings) UseResponseFiles() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.useResponseFiles
+}
+
+// SetUseResponseFiles sets if settings expands response files when parsing
+// flags. When true, each arg of the form @path, that precedes a --, is
+// replaced by the args read from the file at path. A response file's args are
+// separated by whitespace, e.g. one per line, and can be quoted like a
+// shell's: within single quotes everything is literal; outside of quotes, a
+// backslash escapes the next character and, within double quotes, it escapes
+// a double quote or a backslash. A # at the start of an arg starts a comment
+// that runs to the end of the line. A response file can contain other response
+// files, up to 8 deep; a relative path is relative to the directory of the
+// response file that contains it.
+//
+// The origin of a flag's value that was set by a response file includes the
+// file, e.g. "response file build.rsp (flag --define)".
+//
+// If the flags have already been parsed, an ErrFlagsParsed is returned.
+func (s *Settings) SetUseResponseFiles(b bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.flagsParsed {
+		return ErrFlagsParsed
+	}
+	s.useResponseFiles = b
+	return nil
+}
+
+// expandResponseFiles returns args with its response files replaced by their
+// args, along with the response file that each of the returned args came
+// from, src; it's empty for args that didn't come from a response file. Only
+// args that precede a -- are expanded, including a -- in a response file. The
+// dir is the directory relative paths are relative to and depth is how deeply
+// nested the args are.
+func expandResponseFiles(args []string, src, dir string, depth int) (expanded, sources []string, err error) {
+	for i, a := range args {
+		if a == "--" {
+			expanded = append(expanded, args[i:]...)
+			for range args[i:] {
+				sources = append(sources, src)
+			}
+			return expanded, sources, nil
+		}
+		if !strings.HasPrefix(a, "@") || len(a) == 1 {
+			expanded = append(expanded, a)
+			sources = append(sources, src)
+			continue
+		}
+		name := a[1:]
+		if !filepath.IsAbs(name) && dir != "" {
+			name = filepath.Join(dir, name)
+		}
+		if depth >= maxResponseFileDepth {
+			return nil, nil, fmt.Errorf("response file %s: nested too deeply: the max depth is %d", name, maxResponseFileDepth)
+		}
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("response file: %s", err)
+		}
+		fileArgs, err := splitResponseFile(string(b))
+		if err != nil {
+			return nil, nil, fmt.Errorf("response file %s: %s", name, err)
+		}
+		fileArgs, fileSources, err := expandResponseFiles(fileArgs, name, filepath.Dir(name), depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		expanded = append(expanded, fileArgs...)
+		sources = append(sources, fileSources...)
+		// if the response file had a --, the rest of the args follow it
+		for _, fa := range fileArgs {
+			if fa == "--" {
+				expanded = append(expanded, args[i+1:]...)
+				for range args[i+1:] {
+					sources = append(sources, src)
+				}
+				return expanded, sources, nil
+			}
+		}
+	}
+	return expanded, sources, nil
+}
+
+// splitResponseFile splits the contents of a response file into args. See
+// SetUseResponseFiles for the rules.
+func splitResponseFile(v string) ([]string, error) {
+	var (
+		args  []string
+		arg   strings.Builder
+		inArg bool
+		quote rune // the quote that the current arg is in, if any
+	)
+	for i := 0; i < len(v); {
+		r, n := utf8.DecodeRuneInString(v[i:])
+		i += n
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+				continue
+			}
+			arg.WriteRune(r)
+		case r == '\\':
+			if i >= len(v) {
+				return nil, fmt.Errorf("unexpected end of file after \\")
+			}
+			r, n = utf8.DecodeRuneInString(v[i:])
+			i += n
+			// a backslash before a newline continues the line
+			if r == '\n' && quote == 0 {
+				continue
+			}
+			// a backslash in double quotes only escapes characters that
+			// have a special meaning in them.
+			if quote == '"' && r != '"' && r != '\\' {
+				arg.WriteRune('\\')
+			}
+			arg.WriteRune(r)
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+				continue
+			}
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case r == '#' && !inArg:
+			// skip the comment
+			end := strings.IndexByte(v[i:], '\n')
+			if end < 0 {
+				i = len(v)
+				continue
+			}
+			i += end
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// responseFileSources returns the response file that each flag in args was
+// last set by, by the flag's name as it was used. A flag that was last set by
+// the command line isn't included. This assumes the lock has been obtained and
+// that setFlags has been called.
+func (s *Settings) responseFileSources(args, sources []string) map[string]string {
+	m := map[string]string{}
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "-") || a == "-" {
+			continue
+		}
+		var names []string
+		if s.posixFlags && !strings.HasPrefix(a, "--") {
+			// combined short flags: the first one that isn't a bool
+			// flag consumes the rest of the arg
+			for _, r := range a[1:] {
+				f := s.lookupShortFlag(string(r))
+				if f == nil {
+					break
+				}
+				names = append(names, f.Name)
+				if !isBoolFlag(f) {
+					break
+				}
+			}
+		} else {
+			name, _, _ := strings.Cut(strings.TrimLeft(a, "-"), "=")
+			names = append(names, name)
+		}
+		for _, n := range names {
+			if sources[i] == "" {
+				delete(m, n)
+				continue
+			}
+			m[n] = sources[i]
+		}
+	}
+	return m
+}
+
+// UseResponseFiles returns if the standard settings expands response files
+// when parsing flags.
+func UseResponseFiles() bool { return std.UseResponseFiles() }
+
+// SetUseResponseFiles sets if the standard settings expands response files,
+// @path, when parsing flags. See Settings.SetUseResponseFiles.
+func SetUseResponseFiles(b bool) error { return std.SetUseResponseFiles(b) }
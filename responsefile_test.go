@@ -0,0 +1,137 @@
+package contour
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitResponseFile(t *testing.T) {
+	tests := []struct {
+		v        string
+		expected []string
+		err      string
+	}{
+		{"", nil, ""},
+		{"-v\n--port=8080\n", []string{"-v", "--port=8080"}, ""},
+		{"-v --port 8080", []string{"-v", "--port", "8080"}, ""},
+		{"--name 'hello world'", []string{"--name", "hello world"}, ""},
+		{`--name "say \"hi\" \n"`, []string{"--name", `say "hi" \n`}, ""},
+		{`--name hello\ world`, []string{"--name", "hello world"}, ""},
+		{"--name a\\\nb", []string{"--name", "ab"}, ""},
+		{`''`, []string{""}, ""},
+		{"# a comment\n-v # another\n--x=a#b", []string{"-v", "--x=a#b"}, ""},
+		{"--name 'hello", nil, "unterminated ' quote"},
+		{`--name "hello`, nil, `unterminated " quote`},
+		{`--name \`, nil, `unexpected end of file after \`},
+	}
+	for _, test := range tests {
+		args, err := splitResponseFile(test.v)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%q: got %q; want %q", test.v, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%q: got no error; want %q", test.v, test.err)
+			continue
+		}
+		if !reflect.DeepEqual(args, test.expected) {
+			t.Errorf("%q: got %q; want %q", test.v, args, test.expected)
+		}
+	}
+}
+
+func TestResponseFiles(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	files := map[string]string{
+		"build.rsp":      "--define 'a b'\n-v\n@sub/nested.rsp\n",
+		"sub/nested.rsp": "--port=9090\n",
+		"self.rsp":       "@self.rsp\n",
+		"dash.rsp":       "-- @build.rsp\n",
+	}
+	for name, v := range files {
+		fname := filepath.Join(tmpDir, name)
+		os.MkdirAll(filepath.Dir(fname), 0755)
+		err = ioutil.WriteFile(fname, []byte(v), 0644)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	build := filepath.Join(tmpDir, "build.rsp")
+	tests := []struct {
+		args    []string
+		define  string
+		port    int
+		rest    []string
+		origins map[string]string
+		err     string
+	}{
+		{[]string{"@" + build, "x"}, "a b", 9090, []string{"x"}, map[string]string{
+			"define":  "response file " + build + " (flag --define)",
+			"verbose": "response file " + build + " (flag -v)",
+			"port":    "response file " + filepath.Join(tmpDir, "sub", "nested.rsp") + " (flag --port)",
+		}, ""},
+		{[]string{"@" + build, "--define", "c"}, "c", 9090, nil, map[string]string{"define": "flag --define"}, ""},
+		{[]string{"--define", "c", "@" + build}, "a b", 9090, nil, map[string]string{"define": "response file " + build + " (flag --define)"}, ""},
+		{[]string{"--", "@" + build}, "", 8080, []string{"@" + build}, nil, ""},
+		{[]string{"@" + filepath.Join(tmpDir, "dash.rsp"), "@x"}, "", 8080, []string{"@build.rsp", "@x"}, nil, ""},
+		{[]string{"@" + filepath.Join(tmpDir, "self.rsp")}, "", 0, nil, nil, "response file " + filepath.Join(tmpDir, "self.rsp") + ": nested too deeply: the max depth is 8"},
+		{[]string{"@" + filepath.Join(tmpDir, "missing.rsp")}, "", 0, nil, nil, "response file: open " + filepath.Join(tmpDir, "missing.rsp") + ": no such file or directory"},
+	}
+	for _, test := range tests {
+		tst := New("app")
+		tst.SetErrOnMissingConfFile(false)
+		tst.SetPOSIXFlags(true)
+		tst.SetUseResponseFiles(true)
+		tst.SetUsage(func() {})
+		tst.RegisterStringFlag("define", "", "", "", "")
+		tst.RegisterBoolFlag("verbose", "v", false, "false", "")
+		tst.RegisterIntFlag("port", "", 8080, "8080", "")
+		rest, err := tst.ParseFlags(test.args)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%v: got %q; want %q", test.args, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%v: got no error; want %q", test.args, test.err)
+			continue
+		}
+		if v := tst.String("define"); v != test.define {
+			t.Errorf("%v: define: got %q; want %q", test.args, v, test.define)
+		}
+		if v := tst.Int("port"); v != test.port {
+			t.Errorf("%v: port: got %d; want %d", test.args, v, test.port)
+		}
+		if !reflect.DeepEqual(rest, test.rest) {
+			t.Errorf("%v: rest: got %q; want %q", test.args, rest, test.rest)
+		}
+		for k, o := range test.origins {
+			if v := tst.Origin(k); v != o {
+				t.Errorf("%v: %s origin: got %q; want %q", test.args, k, v, o)
+			}
+		}
+	}
+
+	// response files aren't expanded unless settings is set to use them
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.RegisterStringFlag("define", "", "", "", "")
+	rest, err := tst.ParseFlags([]string{"@" + build})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rest) != 1 || !strings.HasPrefix(rest[0], "@") {
+		t.Errorf("got %q; want [@%s]", rest, build)
+	}
+}
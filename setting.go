@@ -31,4 +31,26 @@ type setting struct {
 	IsFlag bool
 	// Alias
 	Alias []string
+	// IsArg: whether or not this is a positional arg. When true, IsConfFileVar,
+	// IsEnvVar, and IsFlag are always false.
+	IsArg bool
+	// Required: whether a positional arg must be provided.
+	Required bool
+	// Variadic: whether a positional arg gets the rest of the args.
+	Variadic bool
+	// Choices are the values the setting can be set to, if it's a setting
+	// whose value is one of a set of values.
+	Choices []string
+	// Hidden settings aren't shown in usage, completion, or documentation.
+	Hidden bool
+	// DeprecatedFor is the setting that replaces this setting, if it's
+	// deprecated.
+	DeprecatedFor string
+	// Origin is where the current value came from, e.g. the default or the
+	// environment variable it was set from.
+	Origin string
+	// SetBy is the type of source that set the current value, which is the
+	// source's own SettingType if it's one that was added, see AddSource; 0
+	// if it's the default.
+	SetBy SettingType
 }
@@ -3,6 +3,8 @@ package contour
 import (
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -13,6 +15,7 @@ import (
 	"github.com/BurntSushi/toml"
 	"github.com/kardianos/osext"
 	"github.com/mohae/cjson"
+	"github.com/titanous/json5"
 	"gopkg.in/yaml.v2"
 )
 
@@ -28,8 +31,8 @@ import (
 // that the are registered as; with higher precedence types being updatable
 // from a lower precedence source, e.g. a Flag setting can be updated from a
 // configuration file, an environment variable, or a flag, while a ConfFileVar
-// setting can only be updated by a configuration file. The order of precedence
-// is:
+// setting can only be updated by a configuration file. The default order of
+// precedence, which can be changed with SetPrecedence, is:
 //    ConfFileVar
 //    EnvVar
 //    Flag
@@ -85,6 +88,9 @@ type Settings struct {
 	name   string
 	mu     sync.RWMutex
 	format Format
+	// A short description of what the settings are for, e.g. the
+	// application's description. It is used in generated documentation.
+	description string
 	// if an attempt to load configuration from a file should error if the file
 	// does not exist.
 	errOnMissingConfFile bool
@@ -121,65 +127,177 @@ type Settings struct {
 	useEnvVars bool
 	// If the settings have been updated from environment variables.
 	envVarsSet bool
+	// If environment variables should also be read from a dotenv file. The
+	// process environment takes precedence over the dotenv file.
+	useDotEnv bool
+	// dotEnvFilename is the name of the dotenv file: defaults to .env
+	dotEnvFilename string
+	// dotEnvPaths is a list of paths in which to check for the dotenv file.
+	dotEnvPaths []string
+	// dotEnvVars are the variables read from the dotenv file. These are never
+	// added to the process environment.
+	dotEnvVars map[string]string
 	// flagset is the set of flags for arg parsing.
 	flagSet *flag.FlagSet
 	// If the settings should be updated from passed args.
 	useFlags bool
 	// If the settings have been updated from the passed args.
 	flagsParsed bool
+	// If flags should be parsed using POSIX/GNU conventions instead of the
+	// flag package's.
+	posixFlags bool
+	// If the built-in completion flag should be added to the flags.
+	completionFlag bool
+	// Where output meant for stdout is written; os.Stdout if nil.
+	out io.Writer
+	// The func warnings are passed to, if one has been set.
+	warnFunc func(msg string)
+	warnSet  bool
+	// The groups of settings that are mutually exclusive or required
+	// together.
+	groups []group
+	// The names of the positional args, in order.
+	args []string
+	// The order of precedence of the sources, from lowest to highest; nil
+	// if it's the default.
+	precedence []SettingType
+	// The file system the configuration file is read from; nil if it's read
+	// from the operating system's.
+	confFS fs.FS
+	// The default configuration, its format, and if its values have been
+	// set.
+	defaultConf       []byte
+	defaultConfFormat Format
+	defaultConfSet    bool
+	// The sources that have been added to settings, in the order they were
+	// added, if they have been set, and, by source, the settings they set,
+	// before they set them.
+	sources    []Source
+	sourcesSet bool
+	sourcePrev map[SettingType]map[string]setting
+	// The profile set by SetProfile, the name of the built-in profile flag
+	// and environment variable, and the profile flag's value, if it was
+	// used.
+	profile     string
+	profileVar  string
+	flagProfile string
+	// The profile the configuration file was set with and the values of
+	// the settings it set, before it set them.
+	confProfile string
+	confPrev    map[string]setting
+	// The source that the configuration was set from, e.g. the
+	// configuration file or a reader; the profile flag reloads it.
+	confSrc Source
+	// The name of the built-in override flag; empty if settings doesn't
+	// have one.
+	overrideFlag string
+	// If @path args should be expanded to the args in the file at path.
+	useResponseFiles bool
+	// The response file that each flag was set by, by the flag's name as it
+	// was used; flags set on the command line aren't included.
+	responseFileFlags map[string]string
 	// The map of variables that capture flag information.
 	flagVars map[string]interface{}
 	// Maps short flags to the long version
 	shortFlags map[string]string
+	// Maps aliases to the setting's name. Single character flag aliases are
+	// also in shortFlags.
+	aliases map[string]string
 	// parsedFlags are flags that were passed and parsed. Short flags are
 	// normalized to the flag name.
 	parsedFlags []string
 	// Settings contains a map of all the configuration settings for this
 	// Setting and each setting's information, including current Value.
 	settings map[string]setting
+	// commands are the settings' commands, by name.
+	commands map[string]*Command
+	// parent is the settings of the command's parent; nil if these settings
+	// aren't a command's.
+	parent *Settings
+	// inheritFlags: if the parent's flags should be added to the flagSet.
+	inheritFlags bool
+	// confSection is the path to the section of the configuration file that
+	// has these settings; empty if the configuration file's top level should
+	// be used.
+	confSection []string
 }
 
 // New provides an initialized Settings named name.
 func New(name string) *Settings {
-	return &Settings{
+	s := &Settings{
 		name:                 name,
 		format:               format,
 		errOnMissingConfFile: true,
 		searchPATH:           true,
+		dotEnvFilename:       ".env",
 		flagSet:              flag.NewFlagSet(name, flag.ContinueOnError),
 		confFileVars:         map[string]struct{}{},
 		flagVars:             map[string]interface{}{},
 		shortFlags:           map[string]string{},
+		aliases:              map[string]string{},
 		settings:             map[string]setting{},
+		commands:             map[string]*Command{},
 	}
+	s.flagSet.Usage = s.defaultUsage
+	return s
 }
 
-// Set updates the settings' configuration from a configuration file and
-// environment variables. This is only run once; subsequent calls will result
-// in no changes. Only settings that are of type ConfFileVar or EnvVar will be
-// affected. This does not handle flags.
+// Set updates the settings' configuration from a configuration file, the
+// sources that have been added to it, see AddSource, and environment
+// variables, in their order of precedence, see SetPrecedence. This is only run
+// once; subsequent calls will result in no changes. Only settings that are of
+// type ConfFileVar or EnvVar will be affected. This does not handle flags:
+// they are set by ParseFlags, wherever they are in the order of precedence.
 //
 // Once the standard settings has been set, updated, it will not update again;
 // subsequent calls will result in nothing being done.
 //
+// If settings doesn't use flags, its groups are checked, see
+// MutuallyExclusive and RequiredTogether.
+//
 // All ConfFileVar, EnvVar, and Flag settings must be registered before calling
 func (s *Settings) Set() error {
+	srcs := s.loadSources()
 	// Set.
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if s.confFileVarsSet && s.envVarsSet {
+	if s.confFileVarsSet && s.envVarsSet && (s.sourcesSet || len(s.sources) == 0) {
 		return nil
 	}
-	err := s.setFromConfFile()
-	if err != nil {
-		return fmt.Errorf("setting configuration from file failed: %s", err)
+	// another Set may have set them while they were being loaded
+	if s.sourcesSet {
+		srcs = nil
 	}
-
-	err = s.updateFromEnvVars()
-	if err != nil {
-		return fmt.Errorf("setting configuration from env failed: %s", err)
+	// the sources are processed in order of precedence
+	for _, typ := range s.sourcePrecedence() {
+		switch typ {
+		case ConfFileVar:
+			err := s.setFromConfFile()
+			if err != nil {
+				return fmt.Errorf("setting configuration from file failed: %w", err)
+			}
+		case EnvVar:
+			err := s.updateFromEnvVars()
+			if err != nil {
+				return fmt.Errorf("setting configuration from env failed: %w", err)
+			}
+		case Flag:
+			// the flags are parsed from the args by ParseFlags
+		default:
+			err := s.setFromSources(typ, srcs)
+			if err != nil {
+				return fmt.Errorf("setting configuration from sources failed: %w", err)
+			}
+		}
+	}
+	if srcs != nil {
+		s.sourcesSet = true
 	}
 
+	// if flags aren't used, the settings are final
+	if !s.useFlags {
+		return s.checkGroups()
+	}
 	return nil
 }
 
@@ -194,6 +312,13 @@ func (s *Settings) Set() error {
 // (_), and the setting's key, e.g. given a settings with the name 'foo', a
 // setting whose key is 'bar' will be updateable with the environment variable
 // FOO_BAR.
+//
+// If settings is configured to use a dotenv file, variables that aren't in the
+// process environment will be looked for in the dotenv file.
+//
+// The values of interface{} settings are decoded as either JSON or YAML, e.g.
+// FOO_LIMITS='{"cpu": 2}'. Values that cannot be parsed as the setting's data
+// type result in a ParseError.
 func (s *Settings) SetFromEnvVars() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -204,43 +329,71 @@ func (s *Settings) updateFromEnvVars() error {
 	if !s.useEnvVars || s.envVarsSet {
 		return nil
 	}
-	var err error
-	for k, v := range s.settings {
-		if !v.IsEnvVar {
-			continue
+	err := s.setDefaultConf()
+	if err != nil {
+		return err
+	}
+	src := envVarSource{s: s}
+	vals, err := src.Load()
+	if err != nil {
+		return err
+	}
+	err = s.setFromSource(EnvVar, src.Name(), vals)
+	if err != nil {
+		return err
+	}
+	err = s.forwardDeprecatedEnvVars()
+	if err != nil {
+		return err
+	}
+	// Rlock isn't sufficient for updating to close it and get a Lock() for update.
+	s.envVarsSet = true
+	return nil
+}
+
+// parseValue returns v, the value of setting k from the source typ under
+// name, e.g. an environment variable's name, parsed as the setting's data
+// type. A flag.Value setting sets itself to v. If v cannot be parsed, a
+// ParseError is returned. This assumes the lock has been obtained.
+func (s *Settings) parseValue(typ SettingType, name, k, v string) (interface{}, error) {
+	val := s.settings[k]
+	switch val.Type {
+	case _bool:
+		b, err := parseBool(v)
+		if err != nil {
+			return nil, ParseError{typ: typ, name: name, v: v, dTyp: val.Type}
 		}
-		tmp := os.Getenv(s.EnvVarName(k))
-		if tmp != "" {
-			switch v.Type {
-			case _bool:
-				b, _ := strconv.ParseBool(tmp)
-				err = s.updateBool(EnvVar, k, b)
-			case _int:
-				i, err := strconv.Atoi(tmp)
-				if err != nil {
-					return fmt.Errorf("getenv %s: %s", s.EnvVarName(k), err)
-				}
-				err = s.updateInt(EnvVar, k, i)
-			case _int64:
-				i, err := strconv.ParseInt(tmp, 10, 64)
-				if err != nil {
-					return fmt.Errorf("getenv %s: %s", s.EnvVarName(k), err)
-				}
-				err = s.updateInt64(EnvVar, k, i)
-			case _string:
-				err = s.updateString(EnvVar, k, tmp)
-			default:
-				return fmt.Errorf("%s: unsupported env variable type: %s", s.EnvVarName(k), v.Type)
-			}
+		return b, nil
+	case _int:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, ParseError{typ: typ, name: name, v: v, dTyp: val.Type}
+		}
+		return i, nil
+	case _int64:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, ParseError{typ: typ, name: name, v: v, dTyp: val.Type}
+		}
+		return i, nil
+	case _string:
+		return v, nil
+	case _interface:
+		// a flag.Value sets itself
+		if fv, ok := val.Value.(flag.Value); ok {
+			err := fv.Set(v)
 			if err != nil {
-				return fmt.Errorf("get env %s: %s", s.EnvVarName(k), err)
+				return nil, ParseError{typ: typ, name: name, v: v, dTyp: val.Type, err: err}
 			}
-			// lock to check next setting, if there is one.
+			return fv, nil
 		}
+		i, err := parseInterface(v)
+		if err != nil {
+			return nil, ParseError{typ: typ, name: name, v: v, dTyp: val.Type, err: err}
+		}
+		return i, nil
 	}
-	// Rlock isn't sufficient for updating to close it and get a Lock() for update.
-	s.envVarsSet = true
-	return nil
+	return nil, fmt.Errorf("%s: unsupported %s type: %s", name, typ, val.Type)
 }
 
 // SetFromConfFile updates the settings' configuration from the configuration
@@ -281,45 +434,110 @@ func (s *Settings) SetFromConfFile() error {
 // TODO:
 //	add a confFileRequired flag
 func (s *Settings) setFromConfFile() error {
-	if !s.useConfFile {
+	if s.confFileVarsSet {
 		return nil
 	}
-
+	if !s.useConfFile {
+		return s.setFromConfSource(nil)
+	}
 	if s.confFilename == "" { // if it wasn't explicitly set, create the name
 		s.confFilename = s.name + "." + s.format.String()
 	}
+	return s.setFromConfSource(confFileSource{s: s})
+}
 
-	b, err := s.readConfFile(s.confFilename)
+// setFromConfSource sets settings from src, the configuration file's source,
+// if it isn't nil, after the default configuration. This assumes the caller
+// already holds the lock.
+func (s *Settings) setFromConfSource(src Source) error {
+	err := s.setDefaultConf()
 	if err != nil {
-		if !s.errOnMissingConfFile && os.IsNotExist(err) { // if a missing conf file is ok, swallow the error
-			return nil
-		}
 		return err
 	}
-
-	cnf, err := unmarshalConfBytes(s.format, b)
-	if err != nil {
-		return fmt.Errorf("%s: %s", s.confFilename, err)
+	if src != nil {
+		vals, err := src.Load()
+		if err != nil {
+			return err
+		}
+		// the profile flag can change the configuration's values
+		s.confSrc = src
+		s.confProfile = s.activeProfile()
+		s.confPrev = make(map[string]setting, len(vals))
+		for k := range vals {
+			s.confPrev[k] = s.settings[k]
+		}
+		// if nothing was loaded, e.g. a missing file that's ok, nothing to do
+		if vals != nil {
+			err = s.setFromSource(ConfFileVar, src.Name(), vals)
+			if err != nil {
+				return err
+			}
+			s.confFileVarsSet = true
+		}
 	}
+	return nil
+}
 
-	// if nothing was returned and no error, nothing to do
-	if cnf == nil {
-		return nil
+// confFileValue returns the value, v, for setting k as the setting's data type,
+// if the configuration format doesn't have a native representation of the
+// value, e.g. a bool setting whose value is the string "yes" or the number 1,
+// or an int setting whose value is a JSON number. If the value cannot be
+// converted, a ParseError is returned.
+func (s *Settings) confFileValue(k string, v interface{}) (interface{}, error) {
+	val, ok := s.settings[k]
+	if !ok {
+		return v, nil
 	}
-	// Go through settings and update setting values.
-	for k, v := range cnf.(map[string]interface{}) {
-		// otherwise update the setting
-		err = s.update(ConfFileVar, k, v)
-		if err != nil {
-			return fmt.Errorf("update setting: %s", err)
+	switch val.Type {
+	case _bool:
+		switch x := v.(type) {
+		case bool:
+			return x, nil
+		case string:
+			b, err := parseBool(x)
+			if err != nil {
+				return nil, ParseError{typ: ConfFileVar, name: k, v: x, dTyp: val.Type}
+			}
+			return b, nil
+		// 1 and 0 are true and false, whatever the format's number type
+		case int, int64, float64:
+			switch fmt.Sprintf("%v", x) {
+			case "1":
+				return true, nil
+			case "0":
+				return false, nil
+			}
 		}
+		return nil, ParseError{typ: ConfFileVar, name: k, v: fmt.Sprintf("%v", v), dTyp: val.Type}
+	case _int, _int64:
+		// JSON's numbers are float64s and TOML's integers are int64s
+		var i int64
+		switch x := v.(type) {
+		case int:
+			i = int64(x)
+		case int64:
+			i = x
+		case float64:
+			if x != float64(int64(x)) {
+				return nil, ParseError{typ: ConfFileVar, name: k, v: fmt.Sprintf("%v", v), dTyp: val.Type}
+			}
+			i = int64(x)
+		default:
+			return v, nil
+		}
+		if val.Type == _int {
+			return int(i), nil
+		}
+		return i, nil
 	}
-	s.confFileVarsSet = true
-	return nil
+	return v, nil
 }
 
 // readConfFile reads the configuration file n.
 func (s *Settings) readConfFile(n string) (b []byte, err error) {
+	if s.confFS != nil {
+		return s.readConfFS(n)
+	}
 	b, err = ioutil.ReadFile(n)
 	if err == nil {
 		return b, nil
@@ -623,7 +841,8 @@ func (s *Settings) IsSet() bool {
 	return true
 }
 
-// SetUsage sets settings' Usage func.
+// SetUsage sets settings' Usage func. By default, the usage generated from
+// settings' registered settings is used, see WriteUsage.
 func (s *Settings) SetUsage(f func()) {
 	s.mu.Lock()
 	s.flagSet.Usage = f
@@ -635,6 +854,22 @@ func (s *Settings) Name() string {
 	return s.name
 }
 
+// Description returns settings' description.
+func (s *Settings) Description() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.description
+}
+
+// SetDescription sets settings' description, a short description of what the
+// settings are for, e.g. what the application does. The description is used
+// in the generated man page and Markdown reference.
+func (s *Settings) SetDescription(v string) {
+	s.mu.Lock()
+	s.description = v
+	s.mu.Unlock()
+}
+
 // IsCoreE returns if setting k is a Core setting. A SettingNotFoundErr will be
 // returned if k doesn't exist in settings.
 func (s *Settings) IsCoreE(k string) (bool, error) {
@@ -727,6 +962,26 @@ func (s *Settings) IsFlag(k string) bool {
 	return b
 }
 
+// OriginE returns where setting k's current value came from, e.g. "default",
+// "conf file app.json", "env var APP_PORT", or "flag --port". A
+// SettingNotFoundErr will be returned if k doesn't exist in settings.
+func (s *Settings) OriginE(k string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	val, ok := s.settings[k]
+	if !ok {
+		return "", SettingNotFoundError{k: k}
+	}
+	return val.Origin, nil
+}
+
+// Origin returns where setting k's current value came from. An empty string
+// will be returned if k doesn't exist in settings.
+func (s *Settings) Origin(k string) string {
+	o, _ := s.OriginE(k)
+	return o
+}
+
 // Exists returns if settings has a setting k.
 func (s *Settings) Exists(k string) bool {
 	s.mu.RLock()
@@ -777,8 +1032,13 @@ func formatFromFilename(s string) (Format, error) {
 //
 // Supported formats:
 //   json
+//   json5
 //   toml
 //   yaml
+//   hcl
+//   ini
+//   properties
+//   xml
 func unmarshalConfBytes(f Format, buff []byte) (interface{}, error) {
 	var ret interface{}
 	switch f {
@@ -800,6 +1060,20 @@ func unmarshalConfBytes(f Format, buff []byte) (interface{}, error) {
 			return nil, err
 		}
 		return ret, nil
+	case JSON5:
+		err := json5.Unmarshal(buff, &ret)
+		if err != nil {
+			return nil, err
+		}
+		return ret, nil
+	case HCL:
+		return unmarshalHCL(buff)
+	case INI:
+		return unmarshalINI(buff)
+	case Properties:
+		return unmarshalProperties(buff)
+	case XML:
+		return unmarshalXML(buff)
 	default:
 		return nil, UnsupportedFormatError{f.String()}
 	}
@@ -998,6 +1272,13 @@ func SetUsage(f func()) { std.SetUsage(f) }
 // Name returns the standard settings' name.
 func Name() string { return std.Name() }
 
+// Description returns the standard settings' description.
+func Description() string { return std.Description() }
+
+// SetDescription sets the standard settings' description, which is used in
+// the generated man page and Markdown reference.
+func SetDescription(v string) { std.SetDescription(v) }
+
 // IsCoreE returns if setting k is a Core setting. A SettingNotFoundErr will be
 // returned if k doesn't exist in the standard settings.
 func IsCoreE(k string) (bool, error) { return std.IsCoreE(k) }
@@ -1031,6 +1312,15 @@ func IsFlagE(k string) (bool, error) { return std.IsFlagE(k) }
 // doesn't exist in the standard settings.
 func IsFlag(k string) bool { return std.IsFlag(k) }
 
+// OriginE returns where setting k's current value came from. A
+// SettingNotFoundErr will be returned if k doesn't exist in the standard
+// settings.
+func OriginE(k string) (string, error) { return std.OriginE(k) }
+
+// Origin returns where setting k's current value came from. An empty string
+// will be returned if k doesn't exist in the standard settings.
+func Origin(k string) string { return std.Origin(k) }
+
 // Exists returns if setting k exists. A false will be be returned if k doesn't
 // exist in the standard settings.
 func Exists(k string) bool { return std.Exists(k) }
@@ -2,6 +2,7 @@ package contour
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -534,11 +535,11 @@ func TestSetFromConfFile(t *testing.T) {
 			},
 			nil,
 			map[string]setting{
-				"var1": setting{Type: _bool, Name: "var1", Value: interface{}(true), IsConfFileVar: true},
-				"var2": setting{Type: _int, Name: "var2", Value: interface{}(42), IsConfFileVar: true},
-				"var3": setting{Type: _string, Name: "var3", Value: interface{}("pan-galactic gargle blaster"), IsConfFileVar: true},
-				"var4": setting{Type: _interface, Name: "var4", Value: interface{}([]int{11, 42}), IsConfFileVar: true},
-				"var5": setting{Type: _interface, Name: "var5", Value: interface{}(map[string]bool{"log": true}), IsConfFileVar: true},
+				"var1": setting{Type: _bool, Name: "var1", Value: interface{}(true), IsConfFileVar: true, SetBy: ConfFileVar, Origin: "conf file " + filepath.Join(tmpDir, "countour_test.json")},
+				"var2": setting{Type: _int, Name: "var2", Value: interface{}(42), IsConfFileVar: true, SetBy: ConfFileVar, Origin: "conf file " + filepath.Join(tmpDir, "countour_test.json")},
+				"var3": setting{Type: _string, Name: "var3", Value: interface{}("pan-galactic gargle blaster"), IsConfFileVar: true, SetBy: ConfFileVar, Origin: "conf file " + filepath.Join(tmpDir, "countour_test.json")},
+				"var4": setting{Type: _interface, Name: "var4", Value: interface{}([]int{11, 42}), IsConfFileVar: true, SetBy: ConfFileVar, Origin: "conf file " + filepath.Join(tmpDir, "countour_test.json")},
+				"var5": setting{Type: _interface, Name: "var5", Value: interface{}(map[string]bool{"log": true}), IsConfFileVar: true, SetBy: ConfFileVar, Origin: "conf file " + filepath.Join(tmpDir, "countour_test.json")},
 				"var6": setting{Type: _int, Name: "var6", Value: interface{}(11), IsConfFileVar: false},
 			},
 		},
@@ -632,8 +633,12 @@ func TestFormatFromFilename(t *testing.T) {
 		{"a cfgfilename with a toml extension", 0, "cfg.toml", "toml", ""},
 		{"a cfgfilename with a toml extension", 0, "cfg.yaml", "yaml", ""},
 		{"a cfgfilename with a toml extension", 0, "cfg.yml", "yaml", ""},
-		{"a cfgfilename with a toml extension", 0, "cfg.xml", "", "xml: unsupported configuration format"},
-		{"a cfgfilename with a toml extension", 0, "cfg.ini", "", "ini: unsupported configuration format"},
+		{"a cfgfilename with an xml extension", 0, "cfg.xml", "xml", ""},
+		{"a cfgfilename with a json5 extension", 0, "cfg.json5", "json5", ""},
+		{"a cfgfilename with a jsonc extension", 0, "cfg.jsonc", "json5", ""},
+		{"a cfgfilename with an ini extension", 0, "cfg.ini", "ini", ""},
+		{"a cfgfilename with a properties extension", 0, "cfg.properties", "properties", ""},
+		{"a cfgfilename with an hcl extension", 0, "cfg.hcl", "hcl", ""},
 	}
 	for _, test := range tests {
 		format, err := formatFromFilename(test.value)
@@ -660,7 +665,9 @@ func TestIsSupportedFormat(t *testing.T) {
 		{"tom format testl", 0, "toml", "true", ""},
 		{"yaml format test", 0, "yaml", "true", ""},
 		{"yml format test", 0, "yml", "true", ""},
-		{"xml format test", 0, "xml", "false", ""},
+		{"json5 format test", 0, "json5", "true", ""},
+		{"jsonc format test", 0, "jsonc", "true", ""},
+		{"xml format test", 0, "xml", "true", ""},
 	}
 	for i, test := range tests {
 		// we don't care about error on this, only the supported part
@@ -681,6 +688,7 @@ func TestUnmarshalCfgBytes(t *testing.T) {
 		expectedErr string
 	}{
 		{"json cfg", JSON, jsonExample, jsonResults, ""},
+		{"json5 cfg", JSON5, json5Example, jsonResults, ""},
 		{"toml cfg", TOML, tomlExample, tomlResults, ""},
 		{"yaml cfg", YAML, yamlExample, yamlResults, ""},
 		{"unsupported cfg", Unsupported, []byte(""), []byte(""), "unsupported: unsupported configuration format"},
@@ -862,3 +870,130 @@ func TestGetEnvVarPaths(t *testing.T) {
 	}
 	os.Unsetenv("TCONTOURPATHT")
 }
+
+func TestEnvVarParseError(t *testing.T) {
+	tests := []struct {
+		name     string
+		dTyp     dataType
+		envValue string
+		expected interface{}
+		err      error
+	}{
+		{"bool", _bool, "yes", true, nil},
+		{"bool", _bool, "OFF", false, nil},
+		{"bool", _bool, "maybe", nil, ParseError{typ: EnvVar, name: "PARSETEST_BOOL", v: "maybe", dTyp: _bool}},
+		{"int", _int, "8080", 8080, nil},
+		{"int", _int, "80a", nil, ParseError{typ: EnvVar, name: "PARSETEST_INT", v: "80a", dTyp: _int}},
+		{"int64", _int64, "x", nil, ParseError{typ: EnvVar, name: "PARSETEST_INT64", v: "x", dTyp: _int64}},
+		{"limits", _interface, `{"cpu":`, nil, ParseError{typ: EnvVar, name: "PARSETEST_LIMITS", v: `{"cpu":`, dTyp: _interface}},
+	}
+	for i, test := range tests {
+		tst := New("parsetest")
+		tst.registerEnvVar(test.dTyp, test.name, nil, "")
+		tst.useConfFile = false
+		os.Setenv(tst.EnvVarName(test.name), test.envValue)
+		err := tst.Set()
+		os.Unsetenv(tst.EnvVarName(test.name))
+		if err != nil {
+			var perr ParseError
+			if !errors.As(err, &perr) {
+				t.Errorf("%d: got %T; want a ParseError", i, err)
+				continue
+			}
+			perr.err = nil
+			if perr != test.err {
+				t.Errorf("%d: got %#v; want %#v", i, perr, test.err)
+			}
+			continue
+		}
+		if test.err != nil {
+			t.Errorf("%d: got no error; want %s", i, test.err)
+			continue
+		}
+		if v := tst.Get(test.name); v != test.expected {
+			t.Errorf("%d: got %v; want %v", i, v, test.expected)
+		}
+	}
+}
+
+func TestConfFileBoolValues(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	tests := []struct {
+		ext      string
+		data     string
+		expected bool
+		err      string
+	}{
+		{"json", `{"debug": true}`, true, ""},
+		{"json", `{"debug": "yes"}`, true, ""},
+		{"json", `{"debug": "off"}`, false, ""},
+		{"json", `{"debug": 1}`, true, ""},
+		{"json", `{"debug": 0}`, false, ""},
+		{"json", `{"debug": "maybe"}`, false, `configuration file var debug: cannot parse "maybe" as bool`},
+		{"json", `{"debug": 42}`, false, `configuration file var debug: cannot parse "42" as bool`},
+		{"json", `{"debug": 1.5}`, false, `configuration file var debug: cannot parse "1.5" as bool`},
+		{"toml", `debug = 1`, true, ""},
+		{"toml", `debug = 0`, false, ""},
+		{"toml", `debug = 2`, false, `configuration file var debug: cannot parse "2" as bool`},
+		{"yaml", "debug: 1\n", true, ""},
+		{"yaml", "debug: 0\n", false, ""},
+	}
+	for i, test := range tests {
+		fname := filepath.Join(tmpDir, "bool."+test.ext)
+		err = ioutil.WriteFile(fname, []byte(test.data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tst := New("bool")
+		tst.RegisterBoolConfFileVar("debug", false)
+		tst.SetConfFilename(fname)
+		err = tst.SetFromConfFile()
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%d: got %q; want %q", i, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%d: got no error; want %q", i, test.err)
+			continue
+		}
+		if v := tst.Bool("debug"); v != test.expected {
+			t.Errorf("%d: got %v; want %v", i, v, test.expected)
+		}
+	}
+}
+
+func TestInterfaceEnvVars(t *testing.T) {
+	tst := New("ifacetest")
+	tst.RegisterSetting("interface{}", "limits", "", nil, "", "", false, true, true, false)
+	tst.RegisterSetting("interface{}", "hosts", "", []string{}, "", "", false, true, true, false)
+	tst.useConfFile = false
+	os.Setenv("IFACETEST_LIMITS", `{"cpu": 2, "mem": "4G"}`)
+	os.Setenv("IFACETEST_HOSTS", "- a.example.com\n- b.example.com")
+	defer os.Unsetenv("IFACETEST_LIMITS")
+	defer os.Unsetenv("IFACETEST_HOSTS")
+	err := tst.Set()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	limits := map[string]interface{}{"cpu": float64(2), "mem": "4G"}
+	if v := tst.Interface("limits"); !reflect.DeepEqual(v, limits) {
+		t.Errorf("limits: got %#v; want %#v", v, limits)
+	}
+	hosts := []interface{}{"a.example.com", "b.example.com"}
+	if v := tst.Interface("hosts"); !reflect.DeepEqual(v, hosts) {
+		t.Errorf("hosts: got %#v; want %#v", v, hosts)
+	}
+	os.Setenv("IFACETEST_LIMITS", `{"cpu": [2}`)
+	tst.envVarsSet = false
+	err = tst.SetFromEnvVars()
+	exp := `env var IFACETEST_LIMITS: cannot parse "{\"cpu\": [2}" as interface{}: invalid character '}' after array element`
+	if err == nil || err.Error() != exp {
+		t.Errorf("malformed: got %v; want %s", err, exp)
+	}
+}
@@ -0,0 +1,600 @@
+package contour
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Source is a source of settings' values, e.g. a database table or a
+// configuration service. The configuration file, environment variables, and
+// flags are Sources, with the SettingTypes ConfFileVar, EnvVar, and Flag. A
+// Source that has been added to settings, see AddSource, has a SettingType of
+// its own, which is its place in settings' order of precedence, see
+// SetPrecedence. Set loads the sources in order of precedence; the flags are
+// the exception: they are parsed from the args by ParseFlags.
+type Source interface {
+	// Name returns the source's name, e.g. "settings table". It's used in
+	// errors and it's the origin of a value that doesn't have one.
+	Name() string
+	// Load returns the source's values by setting name, or alias. A nil map
+	// means that the source has nothing to load. The added sources are
+	// loaded without settings' lock being held, so a slow Load, e.g. a
+	// network request, doesn't block settings' other methods; the values are
+	// applied once they have all been loaded.
+	Load() (map[string]SourceValue, error)
+}
+
+// SourceValue is a value loaded by a Source.
+type SourceValue struct {
+	// The value. A string is parsed as the setting's data type, like an
+	// environment variable's value.
+	Value interface{}
+	// Where the value came from, e.g. "settings table row 3"; if it's empty,
+	// the source's name is used.
+	Origin string
+}
+
+// SourceExistsError occurs when a source is added and settings already has a
+// source with its name.
+type SourceExistsError struct {
+	name string
+}
+
+func (e SourceExistsError) Error() string {
+	return fmt.Sprintf("%s: source exists", e.name)
+}
+
+// SourceNotFoundError occurs when settings doesn't have the source name.
+type SourceNotFoundError struct {
+	name string
+}
+
+func (e SourceNotFoundError) Error() string {
+	return fmt.Sprintf("%s: source not found", e.name)
+}
+
+// AddSource adds src to settings' sources and returns its SettingType, which
+// is used to place it in settings' order of precedence, see SetPrecedence, and
+// is the SetBy of the values it sets. Unless it's placed, an added source is
+// right after the configuration file, and the sources after it, in the order
+// they were added: each one overrides the configuration file and the ones
+// before it. Set loads the sources. A source can only set configuration file
+// settings, e.g. a value for an EnvVar setting that isn't also a ConfFileVar
+// results in an error. The setting's origin is the value's Origin.
+//
+// If settings already has a source with src's name, a SourceExistsError is
+// returned. If settings has already been set from any of its sources, an
+// ErrSourcesSet is returned.
+func (s *Settings) AddSource(src Source) (SettingType, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.confFileVarsSet || s.envVarsSet || s.flagsParsed || s.sourcesSet {
+		return 0, ErrSourcesSet
+	}
+	for _, v := range s.sources {
+		if v.Name() == src.Name() {
+			return 0, SourceExistsError{name: src.Name()}
+		}
+	}
+	s.sources = append(s.sources, src)
+	return firstSourceType + SettingType(len(s.sources)-1), nil
+}
+
+// Sources returns the names of the sources that have been added to settings,
+// in the order they were added.
+func (s *Settings) Sources() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var names []string
+	for _, v := range s.sources {
+		names = append(names, v.Name())
+	}
+	return names
+}
+
+// ReloadSource reloads the values of the source name, which must have been
+// added to settings, e.g. after the values in a KV store have changed. A
+// reloaded value replaces the setting's current value, unless the setting was
+// set by a source with higher precedence than the source, e.g. its flag. A
+// setting that the source no longer has a value for is set back to what it
+// was before the source set it, unless a source with higher precedence has
+// set it since. Once the values have been reloaded, settings' groups are
+// checked, see MutuallyExclusive and RequiredTogether, if settings' values are
+// final. Either all of the values are reloaded or none of them are.
+//
+// If settings doesn't have the source, a SourceNotFoundError is returned.
+func (s *Settings) ReloadSource(name string) error {
+	s.mu.RLock()
+	typ := SettingType(-1)
+	var src Source
+	for i, v := range s.sources {
+		if v.Name() == name {
+			typ, src = firstSourceType+SettingType(i), v
+			break
+		}
+	}
+	s.mu.RUnlock()
+	if src == nil {
+		return SourceNotFoundError{name: name}
+	}
+	l := loadSource(typ, src)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vals, err := s.sourceValues(l)
+	if err != nil {
+		return err
+	}
+	// check them all first so that either all of the values are reloaded or
+	// none of them are.
+	for k := range vals {
+		_, err = s.canSet(typ, k)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+	prev := make(map[string]setting, len(s.settings))
+	for k, v := range s.settings {
+		prev[k] = v
+	}
+	srcPrev := make(map[string]setting, len(s.sourcePrev[typ]))
+	for k, v := range s.sourcePrev[typ] {
+		srcPrev[k] = v
+	}
+	// the settings that the source no longer has a value for
+	for k, v := range s.sourcePrev[typ] {
+		if _, ok := vals[k]; ok {
+			continue
+		}
+		delete(s.sourcePrev[typ], k)
+		if s.settings[k].SetBy != typ {
+			continue
+		}
+		val := s.settings[k]
+		val.Value, val.Origin, val.SetBy = v.Value, v.Origin, v.SetBy
+		s.settings[k] = val
+	}
+	s.setPrev(typ, vals)
+	for k, v := range vals {
+		if s.overridden(s.settings[k], typ) {
+			continue
+		}
+		s.setSourceValue(typ, name, k, v)
+	}
+	// the settings are only final once the flags have been parsed
+	if s.flagsParsed || !s.useFlags {
+		err = s.checkGroups()
+		if err != nil {
+			s.settings, s.sourcePrev[typ] = prev, srcPrev
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// loadedSource is what was loaded from an added source, whose SettingType is
+// typ.
+type loadedSource struct {
+	typ  SettingType
+	name string
+	vals map[string]SourceValue
+	err  error
+}
+
+// loadSource loads src, the added source typ.
+func loadSource(typ SettingType, src Source) loadedSource {
+	vals, err := src.Load()
+	return loadedSource{typ: typ, name: src.Name(), vals: vals, err: err}
+}
+
+// loadSources loads each of the sources that have been added to settings, in
+// the order they were added, unless settings has already been set from them.
+// The lock must not be held: the sources are loaded without it so that a slow
+// source doesn't block settings' other methods.
+func (s *Settings) loadSources() []loadedSource {
+	s.mu.RLock()
+	if s.sourcesSet {
+		s.mu.RUnlock()
+		return nil
+	}
+	srcs := append([]Source(nil), s.sources...)
+	s.mu.RUnlock()
+	loaded := make([]loadedSource, 0, len(srcs))
+	for i, src := range srcs {
+		loaded = append(loaded, loadSource(firstSourceType+SettingType(i), src))
+	}
+	return loaded
+}
+
+// setFromSources sets settings from the values that were loaded from the added
+// source typ, if srcs has them. This assumes the lock has been obtained.
+func (s *Settings) setFromSources(typ SettingType, srcs []loadedSource) error {
+	for _, l := range srcs {
+		if l.typ != typ {
+			continue
+		}
+		vals, err := s.sourceValues(l)
+		if err != nil || vals == nil {
+			return err
+		}
+		s.setPrev(typ, vals)
+		return s.setFromSource(typ, l.name, vals)
+	}
+	return nil
+}
+
+// setPrev records the settings that the added source typ is setting, vals,
+// before it sets them, unless they have already been recorded. This assumes
+// the lock has been obtained.
+func (s *Settings) setPrev(typ SettingType, vals map[string]SourceValue) {
+	if s.sourcePrev == nil {
+		s.sourcePrev = map[SettingType]map[string]setting{}
+	}
+	prev, ok := s.sourcePrev[typ]
+	if !ok {
+		prev = make(map[string]setting, len(vals))
+		s.sourcePrev[typ] = prev
+	}
+	for k := range vals {
+		if _, ok := prev[k]; !ok {
+			prev[k] = s.settings[k]
+		}
+	}
+}
+
+// sourceValues returns the values that were loaded from an added source, by
+// setting name, with their string values parsed as their setting's data type.
+// This assumes the lock has been obtained.
+func (s *Settings) sourceValues(l loadedSource) (map[string]SourceValue, error) {
+	if l.err != nil {
+		return nil, fmt.Errorf("source %s: %w", l.name, l.err)
+	}
+	vals := l.vals
+	if vals == nil {
+		return nil, nil
+	}
+	vals, err := s.resolveAliases(vals)
+	if err != nil {
+		return nil, fmt.Errorf("source %s: %w", l.name, err)
+	}
+	for k, v := range vals {
+		tmp, ok := v.Value.(string)
+		if !ok {
+			continue
+		}
+		// a setting that was set by a source with higher precedence isn't
+		// parsed: a flag.Value would set itself.
+		val, ok := s.settings[k]
+		if !ok || val.Type == _string || s.overridden(val, l.typ) {
+			continue
+		}
+		v.Value, err = s.parseValue(ConfFileVar, k, k, tmp)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", l.name, err)
+		}
+		vals[k] = v
+	}
+	return vals, nil
+}
+
+// setFromSource updates settings with the values loaded from the source name
+// as typ updates. The values must be the settings' data types. This assumes
+// the lock has been obtained.
+func (s *Settings) setFromSource(typ SettingType, name string, vals map[string]SourceValue) error {
+	// sorted so that the first error is always the same one
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// the bool is ignored because a false will always return an error.
+		_, err := s.canUpdate(typ, k)
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		s.setSourceValue(typ, name, k, vals[k])
+	}
+	return nil
+}
+
+// setSourceValue sets setting k to v, a value loaded from the source name, as
+// a typ update. The value's origin is the source's name if it doesn't have
+// one. This assumes the lock has been obtained.
+func (s *Settings) setSourceValue(typ SettingType, name, k string, v SourceValue) {
+	s.setValue(typ, k, v.Value)
+	val := s.settings[k]
+	val.Origin = v.Origin
+	if val.Origin == "" {
+		val.Origin = name
+	}
+	s.settings[k] = val
+}
+
+// resolveAliases returns vals with any aliases replaced by their setting's
+// name. If both a setting's name and an alias are used, the setting's name
+// takes precedence. If more than one of a setting's aliases is used, but not
+// its name, an AliasConflictError is returned. This assumes the lock has been
+// obtained.
+func (s *Settings) resolveAliases(vals map[string]SourceValue) (map[string]SourceValue, error) {
+	m := make(map[string]SourceValue, len(vals))
+	used := map[string][]string{}
+	for k, v := range vals {
+		if n, ok := s.aliases[k]; ok {
+			if _, ok := vals[n]; ok {
+				continue
+			}
+			used[n] = append(used[n], k)
+			k = n
+		}
+		m[k] = v
+	}
+	// sorted so that the error is always the same one
+	keys := make([]string, 0, len(used))
+	for k := range used {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if len(used[k]) > 1 {
+			sort.Strings(used[k])
+			return nil, AliasConflictError{k: k, aliases: used[k]}
+		}
+	}
+	return m, nil
+}
+
+// confFileSource is settings' configuration file as a Source.
+type confFileSource struct {
+	s *Settings
+}
+
+func (c confFileSource) Name() string { return "conf file " + c.s.confFilename }
+
+// Load returns the configuration file's values, with the values that the
+// format doesn't have a native representation of converted to their
+// setting's data type. If the file doesn't exist and that isn't an error, nil
+// is returned.
+func (c confFileSource) Load() (map[string]SourceValue, error) {
+	s := c.s
+	b, err := s.readConfFile(s.confFilename)
+	if err != nil {
+		if !s.errOnMissingConfFile && os.IsNotExist(err) { // if a missing conf file is ok, swallow the error
+			return nil, nil
+		}
+		return nil, err
+	}
+	return s.profileConfValues(s.confFilename, c.Name(), s.format, b, s.confFilename)
+}
+
+// readerSource is configuration read from an io.Reader as a Source.
+type readerSource struct {
+	s      *Settings
+	name   string
+	format Format
+	b      []byte
+}
+
+func (r readerSource) Name() string { return r.name }
+
+// Load returns the configuration's values, like confFileSource's Load. A
+// reader doesn't have a name to look for the profile's file with.
+func (r readerSource) Load() (map[string]SourceValue, error) {
+	return r.s.profileConfValues(r.name, r.name, r.format, r.b, "")
+}
+
+// profileConfValues returns the values of the configuration b, like
+// confValues, with the active profile's values overriding them, see
+// profileValues. This assumes the lock has been obtained.
+func (s *Settings) profileConfValues(name, origin string, f Format, b []byte, fname string) (map[string]SourceValue, error) {
+	m, err := confMap(name, f, b)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	vals, err := s.confMapValues(origin, f, m)
+	if err != nil {
+		return nil, err
+	}
+	// the profile's values override the configuration's
+	profile := s.activeProfile()
+	if profile == "" {
+		return vals, nil
+	}
+	overlay, err := s.profileValues(profile, name, origin, f, m, b, fname)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range overlay {
+		vals[k] = v
+	}
+	return vals, nil
+}
+
+// confValues returns the values of the configuration b, in the format f, with
+// the values that the format doesn't have a native representation of
+// converted to their setting's data type. The name is used in errors and the
+// origin is each value's origin. If b doesn't have any configuration, nil is
+// returned. This assumes the lock has been obtained.
+func (s *Settings) confValues(name, origin string, f Format, b []byte) (map[string]SourceValue, error) {
+	m, err := confMap(name, f, b)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	return s.confMapValues(origin, f, m)
+}
+
+// confMap returns the configuration b, in the format f, as a map; if b doesn't
+// have any configuration, nil is returned. The name is used in errors.
+func confMap(name string, f Format, b []byte) (map[string]interface{}, error) {
+	cnf, err := unmarshalConfBytes(f, b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+
+	// if nothing was returned and no error, nothing to do
+	if cnf == nil {
+		return nil, nil
+	}
+	m, ok := stringMaps(cnf).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: not a map of settings", name)
+	}
+	return m, nil
+}
+
+// confMapValues returns the values of the configuration m, in the format f,
+// converted like confValues's, with each value's origin set to origin. The
+// profiles' sections aren't values, unless there's a setting with their key.
+// This assumes the lock has been obtained.
+func (s *Settings) confMapValues(origin string, f Format, m map[string]interface{}) (map[string]SourceValue, error) {
+	if _, ok := s.settings[profilesKey]; !ok && m[profilesKey] != nil {
+		tmp := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			if k != profilesKey {
+				tmp[k] = v
+			}
+		}
+		m = tmp
+	}
+	// if these are a command's settings, only its section is used.
+	for _, name := range s.confSection {
+		m = confMapSection(f, m, name)
+	}
+	vals := map[string]SourceValue{}
+	for k, v := range m {
+		// command sections belong to the command
+		if _, ok := s.commands[k]; ok {
+			continue
+		}
+		if i := strings.Index(k, "."); i > 0 && f.isFlat() {
+			if _, ok := s.commands[k[:i]]; ok {
+				continue
+			}
+		}
+		vals[k] = SourceValue{Value: v, Origin: origin}
+	}
+	vals, err := s.resolveAliases(vals)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", origin, err)
+	}
+	for k, v := range vals {
+		// all of the values of formats without types are strings, which
+		// are parsed like an environment variable's
+		if tmp, ok := v.Value.(string); ok && !f.isTyped() {
+			if val, ok := s.settings[k]; ok && val.Type != _string {
+				v.Value, err = s.parseValue(ConfFileVar, k, k, tmp)
+				if err != nil {
+					return nil, err
+				}
+				vals[k] = v
+				continue
+			}
+		}
+		v.Value, err = s.confFileValue(k, v.Value)
+		if err != nil {
+			return nil, err
+		}
+		vals[k] = v
+	}
+	return vals, nil
+}
+
+// confMapSection returns the section name of the configuration m, in the
+// format f. The keys of INI and .properties are flat, so the section is the
+// keys that are prefixed with name and a dot, without the prefix, e.g.
+// serve.port is the serve section's port key.
+func confMapSection(f Format, m map[string]interface{}, name string) map[string]interface{} {
+	if !f.isFlat() {
+		sec, _ := m[name].(map[string]interface{})
+		return sec
+	}
+	prefix := name + "."
+	sec := map[string]interface{}{}
+	for k, v := range m {
+		if strings.HasPrefix(k, prefix) {
+			sec[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+	return sec
+}
+
+// flagSource is the settings' flags that were used in the parsed args, as a
+// Source.
+type flagSource struct {
+	s *Settings
+}
+
+// Name returns the flags' source's name.
+func (f flagSource) Name() string { return "flags" }
+
+// Load returns the values of the settings' flags that were used in the parsed
+// args, by setting name. Flags that aren't settings' own, e.g. inherited flags,
+// are skipped. This assumes the lock has been obtained and the args have been
+// parsed.
+func (f flagSource) Load() (map[string]SourceValue, error) {
+	var vals map[string]SourceValue
+	f.s.flagSet.Visit(func(fl *flag.Flag) {
+		k, v, ok := f.s.flagValue(fl)
+		if !ok {
+			return
+		}
+		if vals == nil {
+			vals = map[string]SourceValue{}
+		}
+		if prev, ok := vals[k]; ok && !agrees(fl, v.Value) {
+			v.Origin = prev.Origin
+		}
+		vals[k] = v
+	})
+	return vals, nil
+}
+
+// envVarSource is the environment, and the dotenv file if settings uses one,
+// as a Source.
+type envVarSource struct {
+	s *Settings
+}
+
+func (e envVarSource) Name() string { return "env vars" }
+
+// Load returns the values of the env var settings' environment variables,
+// parsed as their setting's data type. Environment variables that aren't set,
+// or are empty, aren't included.
+func (e envVarSource) Load() (map[string]SourceValue, error) {
+	s := e.s
+	err := s.loadDotEnv()
+	if err != nil {
+		return nil, err
+	}
+	vals := map[string]SourceValue{}
+	for k, v := range s.settings {
+		if !v.IsEnvVar {
+			continue
+		}
+		name := s.EnvVarName(k)
+		tmp := s.getenv(name)
+		if tmp == "" {
+			continue
+		}
+		val, err := s.parseValue(EnvVar, name, k, tmp)
+		if err != nil {
+			return nil, err
+		}
+		vals[k] = SourceValue{Value: val, Origin: s.origin(EnvVar, k)}
+	}
+	return vals, nil
+}
+
+// AddSource adds src to the standard settings' sources and returns its
+// SettingType. See Settings.AddSource.
+func AddSource(src Source) (SettingType, error) { return std.AddSource(src) }
+
+// Sources returns the names of the sources that have been added to the
+// standard settings.
+func Sources() []string { return std.Sources() }
+
+// ReloadSource reloads the values of the standard settings' source name. See
+// Settings.ReloadSource.
+func ReloadSource(name string) error { return std.ReloadSource(name) }
@@ -0,0 +1,377 @@
+package contour
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// mapSource is a Source whose values are in a map.
+type mapSource struct {
+	name string
+	vals map[string]SourceValue
+	err  error
+}
+
+func (m mapSource) Name() string { return m.name }
+
+func (m mapSource) Load() (map[string]SourceValue, error) { return m.vals, m.err }
+
+func TestAddSource(t *testing.T) {
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	table, err := tst.AddSource(mapSource{name: "table"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	service, err := tst.AddSource(mapSource{name: "service"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if table == service || table.String() != "source" {
+		t.Errorf("got %v and %v; want two source types", table, service)
+	}
+	_, err = tst.AddSource(mapSource{name: "table"})
+	if err == nil || err.Error() != "table: source exists" {
+		t.Errorf("got %v; want table: source exists", err)
+	}
+	if v := tst.Sources(); !reflect.DeepEqual(v, []string{"table", "service"}) {
+		t.Errorf("got %v; want [table service]", v)
+	}
+	tst.RegisterIntEnvVar("port", 8080)
+	tst.Set()
+	if _, err = tst.AddSource(mapSource{name: "other"}); err != ErrSourcesSet {
+		t.Errorf("got %v; want %v", err, ErrSourcesSet)
+	}
+}
+
+func TestSetFromSources(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "app.json")
+	err = ioutil.WriteFile(fname, []byte(`{"host": "conf.example.com", "log": "warn"}`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	os.Setenv("APP_LOG", "debug")
+	defer os.Unsetenv("APP_LOG")
+	loadErr := errors.New("connection refused")
+	tests := []struct {
+		sources []Source
+		values  map[string]interface{}
+		origins map[string]string
+		err     string
+	}{
+		{
+			nil,
+			map[string]interface{}{"host": "conf.example.com", "port": 8080, "tls": false, "log": "debug"},
+			map[string]string{"host": "conf file " + fname, "port": "default", "log": "env var APP_LOG"},
+			"",
+		},
+		{
+			[]Source{
+				mapSource{name: "table", vals: map[string]SourceValue{
+					"host":   {Value: "table.example.com", Origin: "table row 1"},
+					"listen": {Value: "9090"},
+					"tls":    {Value: "yes"},
+					"log":    {Value: "info"},
+				}},
+				mapSource{name: "service", vals: map[string]SourceValue{
+					"host": {Value: "service.example.com"},
+				}},
+				mapSource{name: "empty"},
+			},
+			map[string]interface{}{"host": "service.example.com", "port": 9090, "tls": true, "log": "debug"},
+			map[string]string{"host": "service", "port": "table", "tls": "table", "log": "env var APP_LOG"},
+			"",
+		},
+		{
+			[]Source{mapSource{name: "table", vals: map[string]SourceValue{"port": {Value: "http"}}}},
+			nil, nil,
+			`source table: configuration file var port: cannot parse "http" as int`,
+		},
+		{
+			[]Source{mapSource{name: "table", vals: map[string]SourceValue{"user": {Value: "root"}}}},
+			nil, nil,
+			"table: update of user failed: is not a configuration file var",
+		},
+		{
+			[]Source{mapSource{name: "table", err: loadErr}},
+			nil, nil,
+			"source table: connection refused",
+		},
+	}
+	for i, test := range tests {
+		tst := New("app")
+		tst.SetConfFilename(fname)
+		for _, src := range test.sources {
+			tst.AddSource(src)
+		}
+		tst.RegisterStringFlag("host", "", "localhost", "localhost", "")
+		tst.RegisterIntFlag("port", "", 8080, "8080", "")
+		tst.RegisterBoolConfFileVar("tls", false)
+		tst.RegisterStringEnvVar("log", "warn")
+		tst.RegisterSetting("string", "user", "", "", "", "", false, false, true, false)
+		tst.AddAlias("port", "listen")
+		err = tst.Set()
+		if err != nil {
+			if err.Error() != "setting configuration from sources failed: "+test.err {
+				t.Errorf("%d: got %q; want %q", i, err, test.err)
+			}
+			if test.err == loadErr.Error() && !errors.Is(err, loadErr) {
+				t.Errorf("%d: got %v; want it to wrap %v", i, err, loadErr)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%d: got no error; want %q", i, test.err)
+			continue
+		}
+		for k, v := range test.values {
+			if got := tst.Get(k); !reflect.DeepEqual(got, v) {
+				t.Errorf("%d: %s: got %#v; want %#v", i, k, got, v)
+			}
+		}
+		for k, o := range test.origins {
+			if got := tst.Origin(k); got != o {
+				t.Errorf("%d: %s origin: got %q; want %q", i, k, got, o)
+			}
+		}
+	}
+}
+
+func TestReloadSource(t *testing.T) {
+	vals := map[string]SourceValue{"host": {Value: "table.example.com"}, "port": {Value: "9090"}}
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.SetUsage(func() {})
+	tst.AddSource(mapSource{name: "table", vals: vals})
+	tst.RegisterStringFlag("host", "", "localhost", "localhost", "")
+	tst.RegisterIntFlag("port", "", 8080, "8080", "")
+	tst.RegisterBoolConfFileVar("tls", false)
+	err := tst.Set()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, err = tst.ParseFlags([]string{"-host", "flag.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	vals["host"] = SourceValue{Value: "reloaded.example.com"}
+	vals["port"] = SourceValue{Value: "7070", Origin: "table row 2"}
+	err = tst.ReloadSource("table")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the flag has higher precedence
+	if v := tst.String("host"); v != "flag.example.com" {
+		t.Errorf("host: got %q; want flag.example.com", v)
+	}
+	if v := tst.Int("port"); v != 7070 {
+		t.Errorf("port: got %d; want 7070", v)
+	}
+	if o := tst.Origin("port"); o != "table row 2" {
+		t.Errorf("origin: got %q; want table row 2", o)
+	}
+
+	err = tst.ReloadSource("service")
+	if err == nil || err.Error() != "service: source not found" {
+		t.Errorf("got %v; want service: source not found", err)
+	}
+	vals["tls"] = SourceValue{Value: "true"}
+	vals["missing"] = SourceValue{Value: "x"}
+	err = tst.ReloadSource("table")
+	if err == nil || err.Error() != "table: missing: setting not found" {
+		t.Errorf("got %v; want table: missing: setting not found", err)
+	}
+	// none of the values were reloaded
+	if tst.Bool("tls") {
+		t.Error("tls: got true; want false")
+	}
+}
+
+func TestReloadSourceDeleted(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "contourTest")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fname := filepath.Join(tmpDir, "app.json")
+	err = ioutil.WriteFile(fname, []byte(`{"port": 8000}`), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	vals := map[string]SourceValue{
+		"host": {Value: "table.example.com"},
+		"port": {Value: "9090"},
+		"log":  {Value: "info"},
+	}
+	tst := New("app")
+	tst.SetConfFilename(fname)
+	tst.SetUsage(func() {})
+	tst.AddSource(mapSource{name: "table", vals: vals})
+	tst.RegisterStringFlag("host", "", "localhost", "localhost", "")
+	tst.RegisterIntFlag("port", "", 8080, "8080", "")
+	tst.RegisterStringFlag("log", "", "warn", "warn", "")
+	tst.RegisterStringConfFileVar("tls-cert", "")
+	tst.RegisterStringConfFileVar("tls-key", "")
+	tst.RequiredTogether("tls-cert", "tls-key")
+	err = tst.Set()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, err = tst.ParseFlags([]string{"-log", "error"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	delete(vals, "host")
+	delete(vals, "port")
+	delete(vals, "log")
+	err = tst.ReloadSource("table")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the values from before the source set them are restored, unless a
+	// source with higher precedence set them since
+	tests := []struct {
+		k      string
+		v      string
+		origin string
+	}{
+		{"host", "localhost", "default"},
+		{"log", "error", "flag -log"},
+	}
+	for _, test := range tests {
+		if v := tst.String(test.k); v != test.v {
+			t.Errorf("%s: got %q; want %q", test.k, v, test.v)
+		}
+		if o := tst.Origin(test.k); o != test.origin {
+			t.Errorf("%s: origin: got %q; want %q", test.k, o, test.origin)
+		}
+	}
+
+	if v := tst.Int("port"); v != 8000 {
+		t.Errorf("port: got %d; want 8000", v)
+	}
+	if o := tst.Origin("port"); o != "conf file "+fname {
+		t.Errorf("port: origin: got %q; want %q", o, "conf file "+fname)
+	}
+
+	// the groups are checked and none of the values are reloaded if they
+	// aren't satisfied
+	vals["host"] = SourceValue{Value: "reloaded.example.com"}
+	vals["tls-cert"] = SourceValue{Value: "cert.pem"}
+	err = tst.ReloadSource("table")
+	want := "table: tls-cert, tls-key: required together: tls-cert set by table; tls-key not set"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v; want %s", err, want)
+	}
+	if v := tst.String("host"); v != "localhost" {
+		t.Errorf("host: got %q; want localhost", v)
+	}
+	if v := tst.String("tls-cert"); v != "" {
+		t.Errorf("tls-cert: got %q; want \"\"", v)
+	}
+	vals["tls-key"] = SourceValue{Value: "key.pem"}
+	err = tst.ReloadSource("table")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v := tst.String("tls-key"); v != "key.pem" {
+		t.Errorf("tls-key: got %q; want key.pem", v)
+	}
+}
+
+func TestSourcePrecedence(t *testing.T) {
+	os.Setenv("APP_LOG", "debug")
+	defer os.Unsetenv("APP_LOG")
+	tests := []struct {
+		precedence []SettingType
+		args       []string
+		log        string
+		origin     string
+	}{
+		{nil, nil, "debug", "env var APP_LOG"},
+		{[]SettingType{EnvVar, ConfFileVar, Flag}, nil, "info", "table"},
+		{[]SettingType{EnvVar, ConfFileVar, Flag}, []string{"-log", "error"}, "error", "flag -log"},
+		{[]SettingType{EnvVar, Flag, ConfFileVar}, []string{"-log", "error"}, "info", "table"},
+		{[]SettingType{ConfFileVar, EnvVar, firstSourceType, Flag}, nil, "info", "table"},
+		{[]SettingType{ConfFileVar, EnvVar, firstSourceType, Flag}, []string{"-log", "error"}, "error", "flag -log"},
+		{[]SettingType{ConfFileVar, EnvVar, Flag, firstSourceType}, []string{"-log", "error"}, "info", "table"},
+	}
+	for i, test := range tests {
+		tst := New("app")
+		tst.SetErrOnMissingConfFile(false)
+		tst.AddSource(mapSource{name: "table", vals: map[string]SourceValue{"log": {Value: "info"}}})
+		if test.precedence != nil {
+			tst.SetPrecedence(test.precedence...)
+		}
+		tst.RegisterStringFlag("log", "", "warn", "warn", "")
+		err := tst.Set()
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		_, err = tst.ParseFlags(test.args)
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		if v := tst.String("log"); v != test.log {
+			t.Errorf("%d: got %q; want %q", i, v, test.log)
+		}
+		if o := tst.Origin("log"); o != test.origin {
+			t.Errorf("%d: origin: got %q; want %q", i, o, test.origin)
+		}
+	}
+}
+
+// blockingSource's Load signals that it is loading and blocks until it is
+// released.
+type blockingSource struct {
+	loading chan struct{}
+	release chan struct{}
+}
+
+func (b blockingSource) Name() string { return "blocking" }
+
+func (b blockingSource) Load() (map[string]SourceValue, error) {
+	b.loading <- struct{}{}
+	<-b.release
+	return map[string]SourceValue{"host": {Value: "source.example.com"}}, nil
+}
+
+func TestLoadSourceUnlocked(t *testing.T) {
+	src := blockingSource{loading: make(chan struct{}), release: make(chan struct{})}
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.AddSource(src)
+	tst.RegisterStringConfFileVar("host", "localhost")
+	for _, set := range []func() error{tst.Set, func() error { return tst.ReloadSource(src.Name()) }} {
+		done := make(chan error)
+		go func() { done <- set() }()
+		<-src.loading
+		// settings can be read while the source is loading
+		got := make(chan string)
+		go func() { got <- tst.String("host") }()
+		select {
+		case <-got:
+		case <-time.After(time.Second):
+			t.Fatal("timed out getting a setting while a source was loading")
+		}
+		src.release <- struct{}{}
+		if err := <-done; err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if v := tst.String("host"); v != "source.example.com" {
+			t.Errorf("got %q; want source.example.com", v)
+		}
+	}
+}
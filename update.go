@@ -1,6 +1,9 @@
 package contour
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+)
 
 // updateError is any error that happens on an update that isn't one of the
 // following: SettingNotFoundError, CoreUpdateError, or UpdateError. This only
@@ -43,10 +46,39 @@ func (s *Settings) update(typ SettingType, k string, v interface{}) error {
 	if err != nil {
 		return err
 	}
-	val, _ := s.settings[k]
+	s.setValue(typ, k, v)
+	return nil
+}
+
+// setValue sets setting k's value to v, as a typ update, without checking if
+// it can be updated. This assumes that the lock has already been obtained by
+// the caller.
+func (s *Settings) setValue(typ SettingType, k string, v interface{}) {
+	val := s.settings[k]
 	val.Value = v
+	val.Origin = s.origin(typ, k)
+	val.SetBy = typ
 	s.settings[k] = val
-	return nil
+}
+
+// origin returns a description of where setting k's value came from when it
+// is updated by a typ update. This assumes that the lock has already been
+// obtained by the caller.
+func (s *Settings) origin(typ SettingType, k string) string {
+	switch typ {
+	case ConfFileVar:
+		return "conf file " + s.confFilename
+	case EnvVar:
+		name := s.EnvVarName(k)
+		if os.Getenv(name) == "" && s.dotEnvVars[name] != "" {
+			return "dotenv file " + s.dotEnvFilename + " (" + name + ")"
+		}
+		return "env var " + name
+	case Flag:
+		return "flag"
+	default:
+		return "update"
+	}
 }
 
 // UpdateBool updates k with a boo, v. If settings does not have a setting k,
@@ -138,7 +170,8 @@ func (s *Settings) updateString(typ SettingType, k, v string) error {
 //
 // all other settings, for whatever reason, may be any combination of types,
 // e.g. it could be a conf var and a flag. Settings of type conf var, env var
-// or flag can be set if a higher precedence type has not already been set.
+// or flag can be set if neither that type nor a higher precedence type, see
+// SetPrecedence, has already been set.
 //
 // examples:
 //    a setting that IsConfFileVar && IsFlag can be set by a ConfFile if both
@@ -150,6 +183,27 @@ func (s *Settings) updateString(typ SettingType, k, v string) error {
 // k is the key of the setting and typ is the type of update that is being
 // checked, e.g. an update from an env var will have a typ of EnvVar.
 func (s *Settings) canUpdate(typ SettingType, k string) (can bool, err error) {
+	can, err = s.canSet(typ, k)
+	if !can || typ == Basic {
+		return can, err
+	}
+	// a source can't update a setting once it, or a source with higher
+	// precedence, has been set.
+	prec := s.sourcePrecedence()
+	for i := len(prec) - 1; i >= s.rank(typ); i-- {
+		if s.sourceSet(prec[i]) {
+			return false, updateError{typ: typ, k: k, slug: "already set from " + s.sourceName(prec[i])}
+		}
+	}
+	return true, nil
+}
+
+// canSet checks if setting k can be set by a typ update, like canUpdate, but
+// without checking if typ, or a source with higher precedence, has already
+// been set, e.g. for a source that's being reloaded. An added source can set
+// configuration file settings. This assumes that the lock has already been
+// obtained by the caller.
+func (s *Settings) canSet(typ SettingType, k string) (can bool, err error) {
 	// See if the key exists, if it doesn't already exist, it can't be updated.
 	v, ok := s.settings[k]
 	if !ok {
@@ -180,54 +234,33 @@ func (s *Settings) canUpdate(typ SettingType, k string) (can bool, err error) {
 		return false, UpdateError{typ: t, k: k}
 	}
 
-	// check by update type
+	// check by update type; the added sources are configuration
+	if s.isSourceType(typ) {
+		typ = ConfFileVar
+	}
+	var is bool
 	switch typ {
 	case ConfFileVar:
-		if v.IsConfFileVar {
-			if !s.confFileVarsSet && !s.envVarsSet && !s.flagsParsed {
-				return true, nil
-			}
-			var set string
-			if s.flagsParsed {
-				set = "flags"
-				goto confErr
-			}
-			if s.envVarsSet {
-				set = "env vars"
-				goto confErr
-			}
-			set = "the configuration file"
-		confErr:
-			return false, updateError{k: k, slug: fmt.Sprintf("already set from %s", set)}
-		}
-		return false, updateError{typ: typ, k: k, slug: fmt.Sprintf("is not a %s", ConfFileVar)}
+		is = v.IsConfFileVar
 	case EnvVar:
-		if v.IsEnvVar {
-			if !s.envVarsSet && !s.flagsParsed {
-				return true, nil
-			}
-			var set string
-			if s.flagsParsed {
-				set = "flags"
-			} else {
-				set = "env vars"
-			}
-			return false, updateError{typ: typ, k: k, slug: fmt.Sprintf("already set from %s", set)}
-		}
-		return false, updateError{typ: typ, k: k, slug: fmt.Sprintf("is not an %s", EnvVar)}
+		is = v.IsEnvVar
 	case Flag:
-		if v.IsFlag {
-			if !s.flagsParsed {
-				return true, nil
-			}
-			return false, updateError{typ: typ, k: k, slug: "already set from flags"}
+		is = v.IsFlag
+	default:
+		// If it was not one of the above, we return a false. It's better to
+		// not allow an update if the case isn't handled than be too
+		// permissive. Getting here is a sign that something within this
+		// func should be updated and/or fixed.
+		return false, updateError{typ: typ, k: k, slug: "invalid update type"}
+	}
+	if !is {
+		article := "a"
+		if typ == EnvVar {
+			article = "an"
 		}
-		return false, updateError{typ: typ, k: k, slug: fmt.Sprintf("is not a %s", Flag)}
+		return false, updateError{typ: typ, k: k, slug: fmt.Sprintf("is not %s %s", article, typ)}
 	}
-	// If it was not one of the above, we return a false. It's better to not allow
-	// an update if the case isn't handled than be too permissive. Getting here is
-	// a sign that something within this func should be updated and/or fixed.
-	return false, updateError{typ: typ, k: k, slug: "invalid update type"}
+	return true, nil
 }
 
 // UpdateBool updates k with a boo, v. If the standard settings does not have a
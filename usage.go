@@ -0,0 +1,337 @@
+package contour
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The width that usage is wrapped to when the terminal width isn't known.
+const defaultUsageWidth = 80
+
+// WriteUsage writes settings' usage to w. The usage is generated from the
+// registered settings and lists settings' commands, flags, environment
+// variables, and configuration file settings, each in their own group. Each
+// setting includes its type, usage, default, the environment variable and
+// configuration file key it can also be set by, and where its current value
+// came from. The output is wrapped to the width of the terminal, which is
+// taken from the COLUMNS environment variable; if it isn't set, 80 is used.
+//
+// Core and Basic settings cannot be set by the user so they aren't included.
+func (s *Settings) WriteUsage(w io.Writer) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.writeUsage(w)
+}
+
+// PrintUsage writes settings' usage to the output of settings' flagSet, which
+// is os.Stderr. See WriteUsage.
+func (s *Settings) PrintUsage() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.writeUsage(s.flagSet.Output())
+}
+
+// defaultUsage is the flagSet's usage func unless one has been set with
+// SetUsage. The flagSet only calls it while parsing flags, so this assumes
+// the lock has been obtained.
+func (s *Settings) defaultUsage() {
+	s.writeUsage(s.flagSet.Output())
+}
+
+// usageEntry is a term, e.g. a flag, and its description in the usage.
+type usageEntry struct {
+	term string
+	desc []string // each element is a paragraph
+}
+
+// writeUsage writes the usage to w. This assumes the lock has been obtained.
+func (s *Settings) writeUsage(w io.Writer) error {
+	var flags, envVars, confVars []setting
+	for _, v := range s.settings {
+		switch {
+		case v.Hidden:
+		case v.IsFlag:
+			flags = append(flags, v)
+		case v.IsEnvVar:
+			envVars = append(envVars, v)
+		case v.IsConfFileVar:
+			confVars = append(confVars, v)
+		}
+	}
+	var inherited []setting
+	if s.parent != nil && s.inheritFlags {
+		inherited = s.inheritedFlagSettings()
+	}
+
+	flagEntries := s.flagEntries(flags)
+	if _, ok := s.settings[completionFlag]; s.completionFlag && !ok {
+		flagEntries = append(flagEntries, usageEntry{term: "    " + s.longFlag(completionFlag) + " string", desc: []string{completionUsage}})
+	}
+	if s.hasOverrideFlag() {
+		flagEntries = append(flagEntries, usageEntry{term: "    " + s.longFlag(s.overrideFlag) + " key=value", desc: []string{overrideUsage}})
+	}
+	if s.hasProfileFlag() {
+		flagEntries = append(flagEntries, usageEntry{term: "    " + s.longFlag(s.profileVar) + " string", desc: []string{profileUsage}})
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Usage: %s\n", s.synopsis(len(flagEntries) > 0 || len(inherited) > 0))
+
+	width := usageWidth()
+	if len(s.commands) > 0 {
+		var entries []usageEntry
+		for _, k := range sortedCommands(s.commands) {
+			entries = append(entries, usageEntry{term: k, desc: []string{s.commands[k].usage}})
+		}
+		writeUsageGroup(&buf, "Commands", entries, width)
+	}
+	if len(s.args) > 0 {
+		var entries []usageEntry
+		for _, k := range s.args {
+			v := s.settings[k]
+			entries = append(entries, usageEntry{term: argForm(v) + " " + usageType(v), desc: []string{v.Usage}})
+		}
+		writeUsageGroup(&buf, "Arguments", entries, width)
+	}
+	if len(flagEntries) > 0 {
+		writeUsageGroup(&buf, "Flags", flagEntries, width)
+	}
+	if len(inherited) > 0 {
+		writeUsageGroup(&buf, "Inherited flags", s.parent.flagEntries(inherited), width)
+	}
+	if len(envVars) > 0 {
+		var entries []usageEntry
+		for _, v := range sortSettings(envVars) {
+			entries = append(entries, usageEntry{
+				term: strings.TrimSpace(s.EnvVarName(v.Name) + " " + usageType(v)),
+				desc: s.usageDesc(v, false),
+			})
+		}
+		writeUsageGroup(&buf, "Environment variables", entries, width)
+	}
+	if len(confVars) > 0 {
+		var entries []usageEntry
+		for _, v := range sortSettings(confVars) {
+			entries = append(entries, usageEntry{
+				term: strings.TrimSpace(s.confKey(v.Name) + " " + usageType(v)),
+				desc: s.usageDesc(v, false),
+			})
+		}
+		writeUsageGroup(&buf, "Configuration file settings", entries, width)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// synopsis returns how settings' application is invoked, e.g.
+// "app [flags] <command> [args]" or "app [flags] <src> [dst]". This assumes the
+// lock has been obtained.
+func (s *Settings) synopsis(hasFlags bool) string {
+	syn := s.flagSet.Name()
+	if hasFlags {
+		syn += " [flags]"
+	}
+	for _, k := range s.args {
+		syn += " " + argForm(s.settings[k])
+	}
+	if len(s.commands) > 0 {
+		syn += " <command> [args]"
+	}
+	return syn
+}
+
+// flagEntries returns the usage entries for the flags. This assumes the lock
+// has been obtained.
+func (s *Settings) flagEntries(flags []setting) []usageEntry {
+	var entries []usageEntry
+	for _, v := range sortSettings(flags) {
+		term := "    " + s.flagName(v)
+		if v.Short != "" {
+			term = "-" + v.Short + ", " + s.flagName(v)
+		}
+		if typ := usageType(v); typ != "" && v.Type != _bool {
+			term += " " + typ
+		}
+		entries = append(entries, usageEntry{term: term, desc: s.usageDesc(v, true)})
+	}
+	return entries
+}
+
+// inheritedFlagSettings returns the parent's flag settings that settings
+// doesn't have a flag for. If settings has a flag with the same short name,
+// the short name is dropped. This assumes the lock has been obtained.
+func (s *Settings) inheritedFlagSettings() []setting {
+	p := s.parent
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var flags []setting
+	for _, v := range p.settings {
+		if !v.IsFlag || v.Hidden {
+			continue
+		}
+		if _, ok := s.settings[v.Name]; ok {
+			continue
+		}
+		if _, ok := s.settings[s.shortFlags[v.Short]]; ok && v.Short != "" {
+			v.Short = ""
+		}
+		flags = append(flags, v)
+	}
+	return flags
+}
+
+// usageDesc returns the description paragraphs for setting v: its usage and
+// default, followed by its aliases, the other ways it can be set, and where
+// its current value came from. The environment variable is only included for
+// flags. This assumes the lock has been obtained.
+func (s *Settings) usageDesc(v setting, isFlag bool) []string {
+	var desc []string
+	u := v.Usage
+	if v.Default != "" {
+		u = strings.TrimSpace(u + " (default " + v.Default + ")")
+	}
+	if u != "" {
+		desc = append(desc, u)
+	}
+	var info []string
+	if len(v.Alias) > 0 {
+		aliases := v.Alias
+		if v.IsFlag {
+			aliases = s.aliasFlags(v)
+		}
+		info = append(info, "aliases: "+strings.Join(aliases, ", "))
+	}
+	if isFlag && v.IsEnvVar {
+		info = append(info, "env: "+s.EnvVarName(v.Name))
+	}
+	if v.IsConfFileVar {
+		info = append(info, "conf: "+s.confKey(v.Name))
+	}
+	if v.DeprecatedFor != "" {
+		info = append(info, "deprecated: use "+v.DeprecatedFor)
+	}
+	if v.Origin != "" {
+		info = append(info, "value from: "+v.Origin)
+	}
+	if len(info) > 0 {
+		desc = append(desc, "["+strings.Join(info, "; ")+"]")
+	}
+	return desc
+}
+
+// confKey returns the key used for setting k in the configuration file. A
+// command's keys are qualified by the command's section, e.g. serve.port.
+func (s *Settings) confKey(k string) string {
+	return strings.Join(append(append([]string{}, s.confSection...), k), ".")
+}
+
+// usageType returns the type of setting v as shown in the usage.
+func usageType(v setting) string {
+	if v.Type == _interface {
+		return "value"
+	}
+	return v.Type.String()
+}
+
+// sortSettings sorts the settings by name.
+func sortSettings(settings []setting) []setting {
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+	return settings
+}
+
+// sortedCommands returns the names of the commands in lexical order.
+func sortedCommands(commands map[string]*Command) []string {
+	names := make([]string, 0, len(commands))
+	for k := range commands {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeUsageGroup writes a group of entries, under its title, to buf. The
+// descriptions are aligned in a column after the terms; a term that is too
+// long for the column has its description start on the next line. The
+// descriptions are wrapped to width.
+func writeUsageGroup(buf *bytes.Buffer, title string, entries []usageEntry, width int) {
+	const indent = "  "
+	// the description column: after the longest term, within reason
+	col := 0
+	for _, e := range entries {
+		if len(e.term) > col {
+			col = len(e.term)
+		}
+	}
+	col += len(indent) + 2
+	if col > width/3 {
+		col = width / 3
+	}
+	descWidth := width - col
+	if descWidth < 20 {
+		descWidth = 20
+	}
+	pad := strings.Repeat(" ", col)
+
+	fmt.Fprintf(buf, "\n%s:\n", title)
+	for _, e := range entries {
+		var lines []string
+		for _, d := range e.desc {
+			lines = append(lines, wrapUsage(d, descWidth)...)
+		}
+		term := indent + e.term
+		if len(term)+2 > col || len(lines) == 0 {
+			buf.WriteString(term + "\n")
+		} else {
+			buf.WriteString(term + strings.Repeat(" ", col-len(term)) + lines[0] + "\n")
+			lines = lines[1:]
+		}
+		for _, l := range lines {
+			buf.WriteString(pad + l + "\n")
+		}
+	}
+}
+
+// wrapUsage wraps s into lines that are no longer than width, breaking on
+// spaces. Words that are longer than width are not broken.
+func wrapUsage(s string, width int) []string {
+	var (
+		lines []string
+		line  string
+	)
+	for _, word := range strings.Fields(s) {
+		if line == "" {
+			line = word
+			continue
+		}
+		if len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// usageWidth returns the width that usage should be wrapped to: the value of
+// the COLUMNS environment variable if it's set, otherwise 80.
+func usageWidth() int {
+	n, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || n <= 0 {
+		return defaultUsageWidth
+	}
+	return n
+}
+
+// WriteUsage writes the standard settings' usage to w. See Settings.WriteUsage.
+func WriteUsage(w io.Writer) error { return std.WriteUsage(w) }
+
+// PrintUsage writes the standard settings' usage to os.Stderr.
+func PrintUsage() { std.PrintUsage() }
@@ -0,0 +1,105 @@
+package contour
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestWriteUsage(t *testing.T) {
+	os.Setenv("COLUMNS", "60")
+	defer os.Unsetenv("COLUMNS")
+	os.Setenv("APP_HOST", "example.com")
+	defer os.Unsetenv("APP_HOST")
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.RegisterBoolFlag("verbose", "v", false, "false", "verbose output")
+	tst.RegisterIntFlag("port", "p", 8080, "8080", "the port that the server listens on for incoming connections")
+	tst.RegisterStringFlag("log-level", "", "info", "info", "log level")
+	tst.RegisterStringEnvVar("host", "localhost")
+	tst.RegisterBoolConfFileVar("tls", false)
+	tst.AddStringCore("version", "1.0")
+	tst.AddCommand("serve", "run the server", nil)
+	tst.Set()
+	tst.ParseFlags([]string{"-p", "9090"})
+	var buf bytes.Buffer
+	err := tst.WriteUsage(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := `Usage: app [flags] <command> [args]
+
+Commands:
+  serve  run the server
+
+Flags:
+      -log-level string
+                    log level (default info)
+                    [env: APP_LOG-LEVEL; conf: log-level;
+                    value from: default]
+  -p, -port int     the port that the server listens on for
+                    incoming connections (default 8080)
+                    [env: APP_PORT; conf: port; value from:
+                    flag -p]
+  -v, -[no-]verbose
+                    verbose output (default false)
+                    [env: APP_VERBOSE; conf: verbose; value
+                    from: default]
+
+Environment variables:
+  APP_HOST string  (default localhost)
+                   [conf: host; value from: env var
+                   APP_HOST]
+
+Configuration file settings:
+  tls bool  (default false)
+            [conf: tls; value from: default]
+`
+	if buf.String() != expected {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), expected)
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	os.Setenv("APP_HOST", "example.com")
+	defer os.Unsetenv("APP_HOST")
+	tst := New("app")
+	tst.SetErrOnMissingConfFile(false)
+	tst.SetPOSIXFlags(true)
+	tst.RegisterIntFlag("port", "p", 8080, "8080", "port")
+	tst.RegisterStringFlag("name", "n", "", "", "name")
+	tst.RegisterStringEnvVar("host", "localhost")
+	tst.RegisterBoolConfFileVar("tls", false)
+	tst.AddString("basic", "")
+	tst.Set()
+	tst.ParseFlags([]string{"--port", "9090", "-n", "x"})
+	tst.UpdateString("basic", "y")
+	tests := []struct {
+		k      string
+		origin string
+		err    string
+	}{
+		{"port", "flag --port", ""},
+		{"name", "flag -n", ""},
+		{"host", "env var APP_HOST", ""},
+		{"tls", "default", ""},
+		{"basic", "update", ""},
+		{"nope", "", "nope: setting not found"},
+	}
+	for _, test := range tests {
+		o, err := tst.OriginE(test.k)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%s: got %q; want %q", test.k, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%s: got no error; want %q", test.k, test.err)
+			continue
+		}
+		if o != test.origin {
+			t.Errorf("%s: got %q; want %q", test.k, o, test.origin)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package contour
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// The name of the root element of an exported XML configuration.
+const xmlRoot = "conf"
+
+// The key of the text of an element that's a nested key, e.g. the 30 of
+// <timeout unit="s">30</timeout>.
+const xmlTextKey = "#text"
+
+// unmarshalXML returns the XML configuration b as a map of the children of
+// its root element, whose name doesn't matter. An element with child elements
+// or attributes is a nested key, like a JSON object, whose keys are its
+// attributes and children, and its text, if it has any, with the key #text;
+// an element that's repeated is a list; anything else is its text, a string.
+func unmarshalXML(b []byte) (map[string]interface{}, error) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		v, err := xmlElement(d, start)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			// an empty root element is an empty configuration
+			return map[string]interface{}{}, nil
+		}
+		return m, nil
+	}
+}
+
+// xmlElement returns the value of the element start, whose start has been
+// read from d, see unmarshalXML.
+func xmlElement(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	var m map[string]interface{}
+	lists := map[string]bool{}
+	add := func(k string, v interface{}) {
+		if m == nil {
+			m = map[string]interface{}{}
+		}
+		prev, ok := m[k]
+		switch {
+		case !ok:
+			m[k] = v
+		case lists[k]:
+			m[k] = append(prev.([]interface{}), v)
+		default:
+			m[k] = []interface{}{prev, v}
+			lists[k] = true
+		}
+	}
+	for _, attr := range start.Attr {
+		add(attr.Name.Local, attr.Value)
+	}
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := xmlElement(d, t)
+			if err != nil {
+				return nil, err
+			}
+			add(t.Name.Local, v)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if m == nil {
+				return strings.TrimSpace(text.String()), nil
+			}
+			if v := strings.TrimSpace(text.String()); v != "" {
+				add(xmlTextKey, v)
+			}
+			return m, nil
+		}
+	}
+}
+
+// marshalXML returns m, a map of string values by key, as an XML
+// configuration, with a child element of its root for each key, sorted. A
+// key that isn't a valid element name results in an error.
+func marshalXML(m map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if !isXMLName(k) {
+			return nil, fmt.Errorf("%s: not a valid XML element name", k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString("<" + xmlRoot + ">\n")
+	for _, k := range keys {
+		buf.WriteString("\t<" + k + ">")
+		err := xml.EscapeText(&buf, []byte(m[k]))
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("</" + k + ">\n")
+	}
+	buf.WriteString("</" + xmlRoot + ">\n")
+	return buf.Bytes(), nil
+}
+
+// isXMLName returns if k can be used as an element's name: it starts with a
+// letter or an underscore, followed by letters, digits, hyphens, underscores,
+// and dots. Colons are left out, as they're for namespaces.
+func isXMLName(k string) bool {
+	if k == "" {
+		return false
+	}
+	for i, r := range k {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
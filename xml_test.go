@@ -0,0 +1,105 @@
+package contour
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestXML(t *testing.T) {
+	tests := []struct {
+		conf    string
+		profile string
+		name    string
+		port    int
+		db      interface{}
+		err     string
+	}{
+		{
+			`<?xml version="1.0"?>
+<app>
+	<name>app</name>
+	<port>8080</port>
+	<db host="db.example.com">
+		<replica>r1.example.com</replica>
+		<replica>r2.example.com</replica>
+	</db>
+	<profiles>
+		<dev><name>app-dev</name></dev>
+	</profiles>
+</app>`, "",
+			"app", 8080,
+			map[string]interface{}{"host": "db.example.com", "replica": []interface{}{"r1.example.com", "r2.example.com"}},
+			"",
+		},
+		{
+			`<app><db host="db.example.com" port="5432">primary</db></app>`, "", "", 0,
+			map[string]interface{}{"host": "db.example.com", "port": "5432", "#text": "primary"},
+			"",
+		},
+		{`<app><name>app</name><profiles><dev><name>app-dev</name><port>80</port></dev></profiles></app>`, "dev", "app-dev", 80, nil, ""},
+		{`<app/>`, "", "", 0, nil, ""},
+		{`<app><port>many</port></app>`, "", "", 0, nil, `configuration file var port: cannot parse "many" as int`},
+		{`<app><name>app</app>`, "", "", 0, nil, "app.xml: XML syntax error on line 1: element <name> closed by </app>"},
+	}
+	for i, test := range tests {
+		tst := New("app")
+		tst.SetConfFS(fstest.MapFS{"app.xml": {Data: []byte(test.conf)}})
+		tst.SetConfFilename("app.xml")
+		tst.SetProfile(test.profile)
+		tst.RegisterStringConfFileVar("name", "")
+		tst.RegisterIntConfFileVar("port", 0)
+		tst.RegisterInterfaceConfFileVar("db", nil)
+		err := tst.SetFromConfFile()
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%d: got %q; want %q", i, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%d: got no error; want %q", i, test.err)
+			continue
+		}
+		if v := tst.String("name"); v != test.name {
+			t.Errorf("%d: name: got %q; want %q", i, v, test.name)
+		}
+		if v := tst.Int("port"); v != test.port {
+			t.Errorf("%d: port: got %d; want %d", i, v, test.port)
+		}
+		if v := tst.Get("db"); !reflect.DeepEqual(v, test.db) {
+			t.Errorf("%d: db: got %#v; want %#v", i, v, test.db)
+		}
+	}
+}
+
+func TestMarshalXML(t *testing.T) {
+	tests := []struct {
+		m   map[string]string
+		xml string
+		err string
+	}{
+		{map[string]string{"name": "a<b", "db.host": "localhost", "_x-1": ""}, xml.Header + "<conf>\n\t<_x-1></_x-1>\n\t<db.host>localhost</db.host>\n\t<name>a&lt;b</name>\n</conf>\n", ""},
+		{map[string]string{"1x": "a"}, "", "1x: not a valid XML element name"},
+		{map[string]string{"a b": "a"}, "", "a b: not a valid XML element name"},
+		{map[string]string{"-a": "a"}, "", "-a: not a valid XML element name"},
+		{map[string]string{"a:b": "a"}, "", "a:b: not a valid XML element name"},
+	}
+	for i, test := range tests {
+		b, err := marshalXML(test.m)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%d: got %q; want %q", i, err, test.err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%d: got no error; want %q", i, test.err)
+			continue
+		}
+		if string(b) != test.xml {
+			t.Errorf("%d: got %q; want %q", i, b, test.xml)
+		}
+	}
+}